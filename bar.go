@@ -1,14 +1,17 @@
 package uiprogress
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"math/bits"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gosuri/uiprogress/util/strutil"
 )
@@ -29,61 +32,281 @@ var (
 	// RightEnd is the default character in the right most part of the progress indicator
 	RightEnd byte = ']'
 
+	// SecondaryFill is the default character representing completed secondary progress, see Bar.SetSecondary
+	SecondaryFill byte = '#'
+
+	// AppendSep is the default separator rendered before each appended decorator
+	AppendSep = " "
+
+	// PrependSep is the default separator rendered after each prepended decorator
+	PrependSep = " "
+
+	// Pace is the default character that marks where progress is expected to be, see Bar.SetExpected
+	Pace byte = '|'
+
+	// TrailFill is the default character of the cells trailing the head, see Bar.Trail
+	TrailFill = '~'
+
 	// Width is the default width of the progress bar
 	Width = 70
 
+	// UnknownTotal is the total for a bar whose total is not known up front. The bar renders an
+	// indeterminate animation and counts progress without a limit.
+	UnknownTotal = -1
+
 	// ErrMaxCurrentReached is error when trying to set current value that exceeds the total value
 	ErrMaxCurrentReached = errors.New("errors: current value is greater total value")
 )
 
+// OverflowMode is what the I/O wrappers do with bytes transferred beyond the total of a bar, see Bar.Overflow
+type OverflowMode int
+
+const (
+	// OverflowGrow raises the total to the bytes transferred, so the percent is recalculated and the bar
+	// completes when the transfer does
+	OverflowGrow OverflowMode = iota
+
+	// OverflowClamp stops the bar at its total, like the Clamp option
+	OverflowClamp
+
+	// OverflowFail fails the transfer with a ProgressError wrapping an OverflowError
+	OverflowFail
+)
+
+// ProgressError is returned by the I/O wrappers when the bytes transferred cannot be added to the bar. It
+// wraps the cause, such as an OverflowError, and is distinct from the errors of the wrapped reader or
+// writer, which are returned as is.
+type ProgressError struct {
+	// Current is the current value of the bar
+	Current int
+
+	// Attempted is the value the bar was being advanced to
+	Attempted int
+
+	// Total is the total value of the bar
+	Total int
+
+	// Err is the cause of the failure
+	Err error
+}
+
+func (e *ProgressError) Error() string {
+	return fmt.Sprintf("progress bar failure: advancing from %d to %d with total %d: %v", e.Current, e.Attempted, e.Total, e.Err)
+}
+
+// Unwrap returns the cause of the failure
+func (e *ProgressError) Unwrap() error {
+	return e.Err
+}
+
+// OverflowError is returned when advancing a bar beyond its total. It wraps ErrMaxCurrentReached, so
+// errors.Is(err, ErrMaxCurrentReached) still holds.
+type OverflowError struct {
+	// Attempted is the value the bar was being set to
+	Attempted int
+
+	// Current is the current value of the bar, which is left unchanged
+	Current int
+
+	// Total is the total value of the bar
+	Total int
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("%v: attempted %d, current %d, total %d", ErrMaxCurrentReached, e.Attempted, e.Current, e.Total)
+}
+
+// Unwrap returns ErrMaxCurrentReached
+func (e *OverflowError) Unwrap() error {
+	return ErrMaxCurrentReached
+}
+
 // Bar represents a progress bar
 type Bar struct {
-	// Total of the total  for the progress bar
+	// Total of the total  for the progress bar. A negative total means the total is unknown. Use SetTotal to
+	// change the total once the bar is live.
 	Total int
 
 	// LeftEnd is character in the left most part of the progress indicator. Defaults to '['
+	//
+	// Deprecated: use LeftEndRune, which takes precedence when set
 	LeftEnd byte
 
 	// RightEnd is character in the right most part of the progress indicator. Defaults to ']'
+	//
+	// Deprecated: use RightEndRune, which takes precedence when set
 	RightEnd byte
 
 	// Fill is the character representing completed progress. Defaults to '='
+	//
+	// Deprecated: use FillRune, which takes precedence when set
 	Fill byte
 
 	// Head is the character that moves when progress is updated.  Defaults to '>'
+	//
+	// Deprecated: use HeadRune, which takes precedence when set
 	Head byte
 
 	// Empty is the character that represents the empty progress. Default is '-'
+	//
+	// Deprecated: use EmptyRune, which takes precedence when set
 	Empty byte
 
+	// SecondaryFill is the character representing completed secondary progress. Defaults to '#'
+	//
+	// Deprecated: use SecondaryFillRune, which takes precedence when set
+	SecondaryFill byte
+
+	// Pace is the character that marks where progress is expected to be. Defaults to '|'
+	//
+	// Deprecated: use PaceRune, which takes precedence when set
+	Pace byte
+
+	// LeftEndRune, RightEndRune, FillRune, HeadRune, EmptyRune, SecondaryFillRune and PaceRune are the
+	// characters of the bar as runes, allowing glyphs such as '█' or '│'. Each one that is zero falls back to
+	// the byte field of the same name. A space EmptyRune renders a blank empty region, kept in place by the
+	// end characters.
+	LeftEndRune       rune
+	RightEndRune      rune
+	FillRune          rune
+	HeadRune          rune
+	EmptyRune         rune
+	SecondaryFillRune rune
+	PaceRune          rune
+
+	// Priority decides which bars are shown when a container has more bars than lines, see
+	// Progress.SetMaxLines. Bars with a higher priority are shown first. Defaults to 0.
+	Priority int
+
+	// Trail is the number of fill cells right behind the head rendered with TrailRune, giving the head a
+	// trailing effect. Defaults to 0, which renders no trail.
+	Trail int
+
+	// TrailRune is the character of the cells trailing the head. Defaults to '~'
+	TrailRune rune
+
+	// Overflow is what the I/O wrappers, such as ReadUpdater, do when more bytes than the total are
+	// transferred, such as when a Content-Length was wrong. Defaults to OverflowGrow.
+	Overflow OverflowMode
+
+	// PhaseSep is the character marking the end of each phase of a bar created with NewPhasedBar
+	PhaseSep rune
+
+	// AppendSep is the separator rendered before each appended decorator. Defaults to " "
+	AppendSep string
+
+	// PrependSep is the separator rendered after each prepended decorator. Defaults to " "
+	PrependSep string
+
 	// TimeStated is time progress began
 	TimeStarted time.Time
 
-	// Width is the width of the progress bar
+	// Width is the width of the progress bar, counting its ends. A width of 0 fills the line: the bar takes
+	// the columns of the terminal left by its decorators, or of a line of the default Width without a
+	// terminal, and the decorators are truncated when they leave less than minWidth columns to the bar.
 	Width int
 
+	// AlwaysShowHead renders the head right after the left end before any progress is made, rather than
+	// only once progress starts
+	AlwaysShowHead bool
+
+	// WidthPercent is the width of the progress bar as a percent of the terminal width, recomputed on every
+	// render. Width is used when WidthPercent is 0 or the terminal width is unavailable.
+	WidthPercent int
+
 	// UnitFormatter transforms the Current() value to the given unit string
 	UnitFormatter UnitFormatter
 
+	// PercentColorStops are the colors of the percent rendered by AppendColoredPercent, by the percent they
+	// start at in increasing order. Defaults to DefaultPercentColorStops when nil.
+	PercentColorStops []ColorStop
+
+	// NumberPrinter formats the completed percent, such as for a locale. Defaults to DefaultNumberPrinter.
+	NumberPrinter NumberPrinter
+
+	// PairFormatter formats the current and the total values together, taking precedence over the
+	// UnitFormatter in FormattedCurrent and FormattedTotal when set
+	PairFormatter PairFormatter
+
+	// TimeFormatter formats the durations rendered by the time decorators, such as AppendElapsed. Defaults to
+	// strutil.PrettyTime.
+	TimeFormatter func(time.Duration) string
+
 	// timeElased is the time elapsed for the progress
 	timeElapsed time.Duration
+	elapsedBase time.Duration
 	current     int
+	secondary   int
+	frame       int
+	dirty       bool
+	expected    func(time.Duration) int
+	now         func() time.Time
+	err         error
+	failc       chan struct{}
+	phases      []Phase
+	autoFor     time.Duration
+	autoFrom    time.Time
+	milestones  []milestone
+	due         []func(*Bar)
+	stallAfter  time.Duration
+	onStall     []func(*Bar)
+	stalled     bool
+	progressed  time.Time
+	deadline    time.Time
+	countdown   bool
+	notify      func()
+	terminal    int8
+	estimator   Estimator
+	index       int
+	siblings    int
+	items       int
+	itemsTotal  int
+	updated     uint64
+	rates       sampleRing
 
 	mtx *sync.RWMutex
 
-	appendFuncs  []DecoratorFunc
-	prependFuncs []DecoratorFunc
+	appendFuncs  []decorator
+	prependFuncs []decorator
 }
 
 // DecoratorFunc is a function that can be prepended and appended to the progress bar
 type DecoratorFunc func(b *Bar) string
 
+// decorator returns the output of a decorator along with its plain text, which has no escape sequences
+type decorator func(b *Bar) (out, plain string)
+
+// unstyled returns f as a decorator whose plain text is its output
+func unstyled(f DecoratorFunc) decorator {
+	return func(b *Bar) (string, string) {
+		out := f(b)
+		return out, out
+	}
+}
+
+// DecoratorPanicText is rendered in place of the output of a decorator that panics
+var DecoratorPanicText = "!ERR"
+
+// DecoratorPanicHandler, when set, is called with the bar and the recovered value whenever a decorator
+// panics, such as to log the failure. The rest of the bar is rendered as usual.
+var DecoratorPanicHandler func(b *Bar, r interface{})
+
 // UnitFormatter formats the current value to a string representation with units
 type UnitFormatter func(int) string
 
-// NewBar returns a new progress bar
+// NewBar returns a new progress bar. Any negative total is unknown and is set to UnknownTotal.
 func NewBar(total int) *Bar {
-	return &Bar{
+	if total < 0 {
+		total = UnknownTotal
+	}
+	b := &Bar{mtx: &sync.RWMutex{}}
+	b.reset(total)
+	return b
+}
+
+// reset sets every field of the bar to its default, keeping the mutex and the capacity of the decorators
+func (b *Bar) reset(total int) {
+	*b = Bar{
 		Total:         total,
 		Width:         Width,
 		LeftEnd:       LeftEnd,
@@ -91,40 +314,320 @@ func NewBar(total int) *Bar {
 		Head:          Head,
 		Fill:          Fill,
 		Empty:         Empty,
+		SecondaryFill: SecondaryFill,
+		Pace:          Pace,
+		TrailRune:     TrailFill,
+		AppendSep:     AppendSep,
+		PrependSep:    PrependSep,
 		UnitFormatter: DefaultFormatter,
+		TimeFormatter: strutil.PrettyTime,
+
+		dirty:        true,
+		now:          DefaultClock.Now,
+		mtx:          b.mtx,
+		appendFuncs:  clearDecorators(b.appendFuncs),
+		prependFuncs: clearDecorators(b.prependFuncs),
+	}
+}
+
+// Clone returns a new bar with the configuration of b, including its total, characters, formatters and
+// decorators, and none of its progress. The decorators are shared by reference, but adding decorators to
+// the clone does not affect b. The estimator, which holds the samples of b, is not copied.
+func (b *Bar) Clone() *Bar {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	c := *b
+	c.mtx = &sync.RWMutex{}
+	c.TimeStarted = time.Time{}
+	c.timeElapsed, c.elapsedBase, c.current, c.secondary, c.frame = 0, 0, 0, 0, 0
+	c.dirty, c.err, c.failc, c.autoFrom, c.notify, c.estimator = true, nil, nil, time.Time{}, nil, nil
+	c.rates = sampleRing{window: b.rates.window, resolution: b.rates.resolution}
+	c.index, c.siblings, c.items, c.itemsTotal, c.updated = 0, 0, 0, 0, 0
+	c.stalled, c.progressed, c.terminal = false, time.Time{}, 0
+	c.onStall = append(b.onStall[:0:0], b.onStall...)
+	c.milestones, c.due = append([]milestone(nil), b.milestones...), nil
+	for i := range c.milestones {
+		c.milestones[i].fired = false
+	}
+	c.appendFuncs = append([]decorator(nil), b.appendFuncs...)
+	c.prependFuncs = append([]decorator(nil), b.prependFuncs...)
+	return &c
+}
 
-		mtx: &sync.RWMutex{},
+// clearDecorators empties fs, releasing the decorators for garbage collection
+func clearDecorators(fs []decorator) []decorator {
+	for i := range fs {
+		fs[i] = nil
 	}
+	return fs[:0]
 }
 
-// Set the current count of the bar. It returns ErrMaxCurrentReached when trying n exceeds the total value. This is atomic operation and concurancy safe.
+// Set the current count of the bar, see SetCurrent
 func (b *Bar) Set(n int) error {
+	return b.SetCurrent(n)
+}
+
+// SetCurrent sets the current value of the bar. It returns an *OverflowError, which wraps
+// ErrMaxCurrentReached, when n exceeds the total value, leaving the bar unchanged. Like Add, a negative n
+// sets the bar to 0. Setting the value the bar already has is a no-op. This is atomic operation and
+// concurancy safe.
+func (b *Bar) SetCurrent(n int) error {
+	return b.set(n, false)
+}
+
+// set sets the current value, stopping at the total instead of failing when clamp is set
+func (b *Bar) set(n int, clamp bool) error {
 	b.mtx.Lock()
-	defer b.mtx.Unlock()
+	defer b.unlock()
 
-	if n > b.Total {
-		return ErrMaxCurrentReached
+	if n < 0 {
+		n = 0
+	}
+	if b.Total >= 0 && n > b.Total {
+		if !clamp {
+			return &OverflowError{Attempted: n, Current: b.current, Total: b.Total}
+		}
+		n = b.Total
+	}
+	if n == b.current {
+		return nil
+	}
+	b.advance(n)
+	return nil
+}
+
+// Add adds n to the current value of the bar, n may be negative. It returns an *OverflowError, which wraps ErrMaxCurrentReached, when the result exceeds the total value. This is atomic operation and concurancy safe.
+func (b *Bar) Add(n int) error {
+	return b.add(n, false)
+}
+
+// add adds n to the current value, stopping at the total instead of failing when clamp is set
+func (b *Bar) add(n int, clamp bool) error {
+	b.mtx.Lock()
+	defer b.unlock()
+
+	v := b.current + n
+	if b.Total >= 0 && v > b.Total {
+		if !clamp {
+			return &OverflowError{Attempted: v, Current: b.current, Total: b.Total}
+		}
+		v = b.Total
+	}
+	if v < 0 {
+		v = 0
+	}
+	b.advance(v)
+	return nil
+}
+
+// count adds n bytes transferred by one of the I/O wrappers to the bar, handling the bytes beyond the total
+// according to the Overflow mode, or stopping at the total when clamp is set
+func (b *Bar) count(n int, clamp bool) error {
+	if n <= 0 {
+		return nil
+	}
+	switch mode := b.overflow(); {
+	case clamp || mode == OverflowClamp:
+		return b.add(n, true)
+	case mode == OverflowGrow:
+		b.grow(n)
+		return nil
+	}
+	if err := b.add(n, false); err != nil {
+		oerr := err.(*OverflowError)
+		return &ProgressError{Current: oerr.Current, Attempted: oerr.Attempted, Total: oerr.Total, Err: oerr}
 	}
-	b.current = n
 	return nil
 }
 
+// overflow returns the Overflow mode of the bar
+func (b *Bar) overflow() OverflowMode {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.Overflow
+}
+
+// grow adds n to the current value, raising the total when the result exceeds it
+func (b *Bar) grow(n int) {
+	b.mtx.Lock()
+	defer b.unlock()
+	v := b.current + n
+	if b.Total >= 0 && v > b.Total {
+		b.Total = v
+	}
+	b.advance(v)
+}
+
 // Incr increments the current value by 1, time elapsed to current time and returns true. It returns false if the cursor has reached or exceeds total value.
 func (b *Bar) Incr() bool {
 	b.mtx.Lock()
-	defer b.mtx.Unlock()
+	defer b.unlock()
 
 	n := b.current + 1
-	if n > b.Total {
+	if b.Total >= 0 && n > b.Total {
 		return false
 	}
+	b.advance(n)
+	return true
+}
+
+// Consume starts a goroutine that advances the bar by every count received on ch, stopping at the total,
+// and finishes the bar once ch is closed. The goroutine is owned by the bar and exits only when ch is
+// closed, so the sender must close ch when done, even on failure. The returned channel is closed once the
+// bar is finished.
+func (b *Bar) Consume(ch <-chan int) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := range ch {
+			b.add(n, true)
+		}
+		b.Finish()
+	}()
+	return done
+}
+
+// ListenChannel starts a goroutine that advances the bar by every delta received on ch, stopping at the
+// total. The goroutine exits once ch is closed, finishing the bar when finish is set, or once the bar fails,
+// see Fail. The returned channel is closed when the goroutine exits.
+//
+// Each delta is applied before the next one is received, so sends on an unbuffered ch block until the bar is
+// updated. Producers that must never block can send on a buffered ch with a select and a default case, at
+// the cost of the deltas dropped when the buffer is full. Once the bar fails, nothing is received from ch, so
+// producers should stop sending or must not block on sends either.
+func (b *Bar) ListenChannel(ch <-chan int, finish bool) <-chan struct{} {
+	return b.listen(ch, finish, func(n int) { b.add(n, true) })
+}
+
+// ListenValues is like ListenChannel with absolute values, setting the current value of the bar to every
+// value received on ch. Dropping values is harmless here, since the next value supersedes them.
+func (b *Bar) ListenValues(ch <-chan int, finish bool) <-chan struct{} {
+	return b.listen(ch, finish, func(n int) { b.set(n, true) })
+}
+
+// listen applies every value received on ch, see ListenChannel
+func (b *Bar) listen(ch <-chan int, finish bool, apply func(int)) <-chan struct{} {
+	done, failed := make(chan struct{}), b.failed()
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case n, ok := <-ch:
+				if !ok {
+					if finish {
+						b.Finish()
+					}
+					return
+				}
+				apply(n)
+			case <-failed:
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// updates counts the updates of all bars, ordering the bars by recency of update
+var updates uint64
+
+// tick records the start time on the first update, refreshes the time elapsed and marks the bar for
+// redraw. Callers must hold the lock.
+func (b *Bar) tick() {
+	b.dirty = true
+	b.changed()
+	b.updated = atomic.AddUint64(&updates, 1)
 	var t time.Time
 	if b.TimeStarted == t {
-		b.TimeStarted = time.Now()
+		b.TimeStarted = b.now()
+	}
+	// the elapsed time never goes back, even when the clock does
+	if elapsed := b.elapsedBase + since(b.now(), b.TimeStarted); elapsed > b.timeElapsed {
+		b.timeElapsed = elapsed
 	}
-	b.timeElapsed = time.Since(b.TimeStarted)
+}
+
+// advance ticks and moves the current value to n, recording the change for InstantRate
+func (b *Bar) advance(n int) {
+	b.tick()
+	b.rates.record(b.now(), n-b.current)
 	b.current = n
-	return true
+	b.progressed, b.stalled = b.now(), false
+	if b.estimator != nil {
+		b.estimator.Sample(b.now(), n)
+	}
+	b.reachMilestones()
+}
+
+// SetWidth sets the width of the bar, see Width, which is safe while the bar is rendered from other
+// goroutines, unlike setting Width. The bar is drawn with the new width from the next frame, and the
+// container erases the lines of the previous frame, so no characters are left over when the bar narrows.
+// It returns an error wrapping ErrInvalidBar, leaving the width unchanged, when n is negative.
+func (b *Bar) SetWidth(n int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: width %d is negative", ErrInvalidBar, n)
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if n != b.Width {
+		b.Width = n
+		b.dirty = true
+		b.changed()
+	}
+	return nil
+}
+
+// SetTotal sets the total value of the bar. Once the bar is rendered or updated from other goroutines,
+// the total must only be changed using SetTotal. A negative total is unknown, see UnknownTotal.
+func (b *Bar) SetTotal(n int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if n != b.Total {
+		b.Total = n
+		b.dirty = true
+		b.changed()
+	}
+}
+
+// changed tells the container of the bar that the bar changed, see Progress.Wait. Callers must hold the lock.
+func (b *Bar) changed() {
+	if b.notify != nil {
+		b.notify()
+	}
+}
+
+// setNotify sets the function called by changed
+func (b *Bar) setNotify(notify func()) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.notify = notify
+}
+
+// SetItems sets an items counter kept alongside the current value, such as the files done out of the files
+// of a download whose bar counts bytes. A negative total means the number of items is unknown. The counter
+// does not affect the bar and is rendered with AppendItems.
+func (b *Bar) SetItems(done, total int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if done != b.items || total != b.itemsTotal {
+		b.items, b.itemsTotal = done, total
+		b.dirty = true
+	}
+}
+
+// Items returns the items counter, see SetItems
+func (b *Bar) Items() (done, total int) {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.items, b.itemsTotal
+}
+
+// state returns the current and the total values of the bar read together
+func (b *Bar) state() (current, total int) {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.current, b.Total
 }
 
 // Current returns the current progress of the bar
@@ -134,11 +637,130 @@ func (b *Bar) Current() int {
 	return b.current
 }
 
+// SetClock sets the clock the bar uses to read the current time, see SetNowFunc
+func (b *Bar) SetClock(c Clock) *Bar {
+	return b.SetNowFunc(c.Now)
+}
+
+// SetNowFunc sets the function the bar uses to read the current time, which defaults to the Now method of
+// DefaultClock. It allows the time elapsed and the rate to be controlled in tests.
+func (b *Bar) SetNowFunc(now func() time.Time) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.now = now
+	return b
+}
+
+// clockNow returns the current time read from the clock of the bar, for callers not holding the lock
+func (b *Bar) clockNow() time.Time {
+	b.mtx.RLock()
+	now := b.now
+	b.mtx.RUnlock()
+	return now()
+}
+
+// Finish completes the bar by setting the current value to the total. When the total is unknown, the
+// total is set to the current value instead.
+func (b *Bar) Finish() {
+	b.mtx.Lock()
+	defer b.unlock()
+	if b.Total < 0 {
+		b.Total = b.current
+	}
+	b.advance(b.Total)
+}
+
+// Fail marks the bar as failed with err, leaving the current value as is. Only the first error is kept.
+func (b *Bar) Fail(err error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.err == nil {
+		b.err = err
+		b.dirty = true
+		b.changed()
+		if b.failc != nil {
+			close(b.failc)
+		}
+	}
+}
+
+// failed returns a channel that is closed once the bar fails, see Fail
+func (b *Bar) failed() <-chan struct{} {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.failc == nil {
+		b.failc = make(chan struct{})
+		if b.err != nil {
+			close(b.failc)
+		}
+	}
+	return b.failc
+}
+
+// Err returns the error the bar failed with, or nil when the bar has not failed
+func (b *Bar) Err() error {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.err
+}
+
+// IsCompleted returns true when the current value has reached the total value
+func (b *Bar) IsCompleted() bool {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.Total >= 0 && b.current >= b.Total
+}
+
+// lastUpdate returns a number that is larger for bars updated more recently
+func (b *Bar) lastUpdate() uint64 {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.updated
+}
+
+// takeDirty reports whether the bar changed since the last call. Bars with an unknown total are always
+// dirty since they animate on every frame. The callbacks due are left to dispatch.
+func (b *Bar) takeDirty() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.autoAdvance()
+	b.checkStall()
+	dirty := b.dirty || b.Total < 0
+	b.dirty = false
+	return dirty
+}
+
+// resolveTotal sets an unknown total to the current value, completing the bar
+func (b *Bar) resolveTotal() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.Total < 0 {
+		b.Total = b.current
+		b.dirty = true
+	}
+}
+
+// Rate returns the average progress per second since the bar started
+func (b *Bar) Rate() float64 {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	if b.timeElapsed <= 0 {
+		return 0
+	}
+	return float64(b.current) / b.timeElapsed.Seconds()
+}
+
 // AppendFunc runs the decorator function and renders the output on the right of the progress bar
 func (b *Bar) AppendFunc(f DecoratorFunc) *Bar {
+	return b.appendDecorator(unstyled(f))
+}
+
+// appendDecorator appends d, which may write escape sequences left out of its plain text
+func (b *Bar) appendDecorator(d decorator) *Bar {
 	b.mtx.Lock()
 	defer b.mtx.Unlock()
-	b.appendFuncs = append(b.appendFuncs, f)
+	b.appendFuncs = append(b.appendFuncs, d)
+	b.dirty = true
 	return b
 }
 
@@ -150,10 +772,39 @@ func (b *Bar) AppendCompleted() *Bar {
 	return b
 }
 
-// AppendElapsed appends the time elapsed the be progress bar
+// AppendRate appends the rate of progress per second to the progress bar
+func (b *Bar) AppendRate() *Bar {
+	b.AppendFunc(func(b *Bar) string {
+		return b.RateString()
+	})
+	return b
+}
+
+// AppendElapsed appends the time elapsed the be progress bar, which keeps ticking between updates, see
+// ElapsedSinceStart
 func (b *Bar) AppendElapsed() *Bar {
 	b.AppendFunc(func(b *Bar) string {
-		return strutil.PadLeft(b.TimeElapsedString(), 5, ' ')
+		return strutil.PadLeft(b.TimeFormatter(b.ElapsedSinceStart()), 5, ' ')
+	})
+	return b
+}
+
+// AppendTimeRemaining appends the time remaining to the progress bar, see TimeRemaining
+func (b *Bar) AppendTimeRemaining() *Bar {
+	b.AppendFunc(func(b *Bar) string {
+		return strutil.PadLeft(b.TimeRemainingString(), 5, ' ')
+	})
+	return b
+}
+
+// AppendItems appends the items counter set with SetItems to the progress bar, e.g. "17/50"
+func (b *Bar) AppendItems() *Bar {
+	b.AppendFunc(func(b *Bar) string {
+		done, total := b.Items()
+		if total < 0 {
+			return strconv.Itoa(done)
+		}
+		return fmt.Sprintf("%d/%d", done, total)
 	})
 	return b
 }
@@ -162,7 +813,8 @@ func (b *Bar) AppendElapsed() *Bar {
 func (b *Bar) PrependFunc(f DecoratorFunc) *Bar {
 	b.mtx.Lock()
 	defer b.mtx.Unlock()
-	b.prependFuncs = append(b.prependFuncs, f)
+	b.prependFuncs = append(b.prependFuncs, unstyled(f))
+	b.dirty = true
 	return b
 }
 
@@ -174,139 +826,1264 @@ func (b *Bar) PrependCompleted() *Bar {
 	return b
 }
 
-// PrependElapsed prepends the time elapsed to the begining of the bar
+// PrependElapsed prepends the time elapsed to the begining of the bar, which keeps ticking between updates,
+// see ElapsedSinceStart
 func (b *Bar) PrependElapsed() *Bar {
 	b.PrependFunc(func(b *Bar) string {
-		return strutil.PadLeft(b.TimeElapsedString(), 5, ' ')
+		return strutil.PadLeft(b.TimeFormatter(b.ElapsedSinceStart()), 5, ' ')
 	})
 	return b
 }
 
-// Bytes returns the byte presentation of the progress bar
-func (b *Bar) Bytes() []byte {
-	var completedWidth int = 0
-	if b.Current() > 0 {
-		completedWidth = int(float64(b.Width) * (b.CompletedPercent() / 100.00))
-	}
-	//completedWidth := int(float64(b.Width) * (float64(b.Current()) / float64(b.Total)))
-
-	// add fill and empty bits
-	var buf bytes.Buffer
-	for i := 0; i < completedWidth; i++ {
-		buf.WriteByte(b.Fill)
-	}
-	for i := 0; i < b.Width-completedWidth; i++ {
-		buf.WriteByte(b.Empty)
-	}
+// PrependIndex prepends the position of the bar among the bars of its Progress, e.g. "[3/50]". The
+// position follows the order the bars were added in and updates as bars are added and removed. Nothing is
+// rendered for a bar that is not part of a Progress.
+func (b *Bar) PrependIndex() *Bar {
+	b.PrependFunc(func(b *Bar) string {
+		b.mtx.RLock()
+		defer b.mtx.RUnlock()
+		if b.index == 0 {
+			return ""
+		}
+		return fmt.Sprintf("[%d/%d]", b.index, b.siblings)
+	})
+	return b
+}
 
-	// set head bit
-	pb := buf.Bytes()
-	if completedWidth > 0 && completedWidth < b.Width {
-		pb[completedWidth-1] = b.Head
+// setIndex sets the position of the bar among the n bars of its Progress
+func (b *Bar) setIndex(i, n int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if i != b.index || n != b.siblings {
+		b.index, b.siblings = i, n
+		b.dirty = true
 	}
+}
 
-	// set left and right ends bits
-	pb[0], pb[len(pb)-1] = b.LeftEnd, b.RightEnd
-
-	// render append functions to the right of the bar
-	for _, f := range b.appendFuncs {
-		pb = append(pb, ' ')
-		pb = append(pb, []byte(f(b))...)
-	}
+// ThrottleDecorator returns a decorator that caches the output of f and calls f again only after the interval
+// every has elapsed, according to the clock of the bar, see SetNowFunc. It is meant for decorators that are
+// expensive to compute. The cache is shared by every bar the decorator is added to.
+func ThrottleDecorator(f DecoratorFunc, every time.Duration) DecoratorFunc {
+	var (
+		mtx  sync.Mutex
+		last time.Time
+		out  string
+	)
+	return func(b *Bar) string {
+		b.mtx.RLock()
+		now := b.now()
+		b.mtx.RUnlock()
 
-	// render prepend functions to the left of the bar
-	for _, f := range b.prependFuncs {
-		args := []byte(f(b))
-		args = append(args, ' ')
-		pb = append(args, pb...)
+		mtx.Lock()
+		defer mtx.Unlock()
+		// a clock set back refreshes the output rather than freezing it until the clock catches up
+		if d := now.Sub(last); last.IsZero() || d >= every || d < 0 {
+			out, last = f(b), now
+		}
+		return out
 	}
-	return pb
 }
 
-// String returns the string representation of the bar
-func (b *Bar) String() string {
-	return string(b.Bytes())
+// Bytes returns the byte presentation of the progress bar, with the characters of the bar encoded as UTF-8
+func (b *Bar) Bytes() []byte {
+	p := b.render(false)
+	b.dispatch()
+	return p
 }
 
-// CompletedPercent return the percent completed
-func (b *Bar) CompletedPercent() float64 {
-	return (float64(b.Current()) / float64(b.Total)) * 100.00
+// PlainString returns the string representation of the bar as printable text, with no escape sequences. The
+// bar is rendered without the colors of the built-in decorators, so the output is stable for comparisons such
+// as golden tests. The escape sequences written by decorators added with AppendFunc and PrependFunc are
+// kept, see strutil.StripANSI.
+func (b *Bar) PlainString() string {
+	p := b.render(true)
+	b.dispatch()
+	return string(p)
 }
 
-// CompletedPercentString returns the formatted string representation of the completed percent
-func (b *Bar) CompletedPercentString() string {
-	return fmt.Sprintf("%3.f%%", b.CompletedPercent())
-}
+// cellPool holds the scratch cells the bars are drawn in before being encoded
+var cellPool = sync.Pool{New: func() interface{} { return new([]rune) }}
 
-// TimeElapsed returns the time elapsed
-func (b *Bar) TimeElapsed() time.Duration {
-	b.mtx.RLock()
-	defer b.mtx.RUnlock()
-	return b.timeElapsed
+// render renders the bar, without escape sequences when plain is set, see renderLines
+func (b *Bar) render(plain bool) []byte {
+	out, text := b.renderLines(!plain, plain)
+	if plain {
+		return text
+	}
+	return out
 }
 
-// TimeElapsedString returns the formatted string represenation of the time elapsed
-func (b *Bar) TimeElapsedString() string {
-	return strutil.PrettyTime(b.TimeElapsed())
+// renderLines renders the bar when styled is set and its plain text when plain is set, running the
+// decorators and drawing the bar once for both. The decorators are run first, so each line is written in one
+// pass into a buffer of the right size. The callbacks due are left to dispatch, so the container can run them
+// once unlocked.
+func (b *Bar) renderLines(styled, plain bool) (out, text []byte) {
+	b.mtx.Lock()
+	b.autoAdvance()
+	b.checkStall()
+	b.mtx.Unlock()
+
+	var prepends, appends, plainPrepends, plainAppends [8]string
+	pre, plainPre := b.decorations(prepends[:0], plainPrepends[:0], b.prependFuncs)
+	post, plainPost := b.decorations(appends[:0], plainAppends[:0], b.appendFuncs)
+
+	width, cols := b.width(), 0
+	if width == 0 {
+		width, cols = fillWidth(plainPre, plainPost, b.PrependSep, b.AppendSep)
+	}
+	scratch := cellPool.Get().(*[]rune)
+	cells := b.cells((*scratch)[:0], width)
+	if styled {
+		out = b.join(pre, post, cells, cols)
+	}
+	if plain {
+		text = b.join(plainPre, plainPost, cells, cols)
+	}
+	*scratch = cells
+	cellPool.Put(scratch)
+	return out, text
 }
 
-func (b *Bar) ReadUpdater(input io.Reader) io.Reader {
-	return &ReadProgressor{
-		bar:   b,
-		input: input,
+// join writes the outputs of the decorators around the cells of the bar, cut to cols columns when positive
+func (b *Bar) join(pre, post []string, cells []rune, cols int) []byte {
+	size := 0
+	for _, out := range pre {
+		size += len(out) + len(b.PrependSep)
+	}
+	for _, r := range cells {
+		if r < utf8.RuneSelf {
+			size++
+		} else {
+			size += utf8.UTFMax
+		}
 	}
+	for _, out := range post {
+		size += len(b.AppendSep) + len(out)
+	}
+	pb := make([]byte, 0, size)
 
+	// the last prepend function renders the leftmost output
+	for i := len(pre) - 1; i >= 0; i-- {
+		pb = append(pb, pre[i]...)
+		pb = append(pb, b.PrependSep...)
+	}
+	var enc [utf8.UTFMax]byte
+	for _, r := range cells {
+		if r < utf8.RuneSelf {
+			pb = append(pb, byte(r))
+			continue
+		}
+		n := utf8.EncodeRune(enc[:], r)
+		pb = append(pb, enc[:n]...)
+	}
+	for _, out := range post {
+		pb = append(pb, b.AppendSep...)
+		pb = append(pb, out...)
+	}
+	if cols > 0 {
+		pb = truncateWidth(pb, cols)
+	}
+	return pb
 }
 
+// fillWidth returns the width of a bar filling the line, see Width, along with the columns of the line
+func fillWidth(pre, post []string, prependSep, appendSep string) (width, cols int) {
+	cols, ok := terminalWidth()
+	if !ok {
+		cols = Width
+	}
+	width = cols
+	for _, out := range pre {
+		width -= displayWidth([]byte(out)) + utf8.RuneCountInString(prependSep)
+	}
+	for _, out := range post {
+		width -= utf8.RuneCountInString(appendSep) + displayWidth([]byte(out))
+	}
+	if width < minWidth {
+		width = minWidth
+	}
+	return width, cols
+}
+
+// cells appends the characters of the bar of the given width, without the decorators, to cells
+func (b *Bar) cells(cells []rune, width int) []rune {
+	current, total := b.state()
+	if total < 0 {
+		cells = b.indeterminate(cells, width)
+	} else {
+		var completedWidth int = 0
+		if current > 0 && total > 0 {
+			if current > total {
+				current = total
+			}
+			completedWidth, _ = mulDiv(current, width, total)
+		}
+		if b.isCountdown() {
+			completedWidth = width - completedWidth
+		}
+
+		secondaryWidth := b.secondaryWidth(width)
+		if secondaryWidth > completedWidth {
+			secondaryWidth = completedWidth
+		}
+
+		// add secondary fill, fill and empty cells
+		for i := 0; i < secondaryWidth; i++ {
+			cells = append(cells, glyph(b.SecondaryFillRune, b.SecondaryFill))
+		}
+		for i := secondaryWidth; i < completedWidth; i++ {
+			cells = append(cells, glyph(b.FillRune, b.Fill))
+		}
+		for i := 0; i < width-completedWidth; i++ {
+			cells = append(cells, glyph(b.EmptyRune, b.Empty))
+		}
+
+		if len(b.phases) > 1 {
+			b.markPhases(cells, width, total)
+		}
+
+		// set pace and head cells
+		if expectedWidth := b.expectedWidth(width); expectedWidth > 0 && expectedWidth < width {
+			cells[expectedWidth-1] = glyph(b.PaceRune, b.Pace)
+		}
+		if completedWidth > 0 && completedWidth < width {
+			cells[completedWidth-1] = glyph(b.HeadRune, b.Head)
+			fill := glyph(b.FillRune, b.Fill)
+			for i := completedWidth - 2; i >= 0 && i >= completedWidth-1-b.Trail; i-- {
+				if cells[i] == fill {
+					cells[i] = b.TrailRune
+				}
+			}
+		}
+		if b.AlwaysShowHead && completedWidth <= 1 && width > 2 {
+			// the left end takes the first cell, so the head sits right after it
+			cells[1] = glyph(b.HeadRune, b.Head)
+		}
+	}
+
+	// set left and right ends cells
+	cells[0], cells[len(cells)-1] = glyph(b.LeftEndRune, b.LeftEnd), glyph(b.RightEndRune, b.RightEnd)
+	return cells
+}
+
+// decorations appends the output of the decorators fs to outs and their plain text to plains, skipping
+// empty output
+func (b *Bar) decorations(outs, plains []string, fs []decorator) ([]string, []string) {
+	for _, f := range fs {
+		if out, plain := b.decorate(f); out != "" {
+			outs, plains = append(outs, out), append(plains, plain)
+		}
+	}
+	return outs, plains
+}
+
+// decorate returns the output of the decorator f and its plain text. A panic in f is recovered and reported
+// to DecoratorPanicHandler, and DecoratorPanicText is rendered in its place.
+func (b *Bar) decorate(f decorator) (out, plain string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if h := DecoratorPanicHandler; h != nil {
+				h(b, r)
+			}
+			out, plain = DecoratorPanicText, DecoratorPanicText
+		}
+	}()
+	return f(b)
+}
+
+// prepended renders the prepend functions, each followed by the separator and skipping empty output
+func (b *Bar) prepended(plain bool) string {
+	outs, plains := b.decorations(nil, nil, b.prependFuncs)
+	if plain {
+		outs = plains
+	}
+	var s string
+	for _, out := range outs {
+		s = out + b.PrependSep + s
+	}
+	return s
+}
+
+// SetSecondary sets a secondary progress value, such as the bytes verified of a download, rendered with
+// the SecondaryFill character behind the primary progress
+func (b *Bar) SetSecondary(n int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if n != b.secondary {
+		b.secondary = n
+		b.dirty = true
+	}
+}
+
+// Secondary returns the secondary progress value of the bar
+func (b *Bar) Secondary() int {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.secondary
+}
+
+// secondaryWidth returns the number of cells covered by the secondary progress
+func (b *Bar) secondaryWidth(width int) int {
+	b.mtx.RLock()
+	secondary, total := b.secondary, b.Total
+	b.mtx.RUnlock()
+	if secondary <= 0 || total <= 0 {
+		return 0
+	}
+	if secondary > total {
+		secondary = total
+	}
+	w, _ := mulDiv(secondary, width, total)
+	return w
+}
+
+// SetExpected sets the function that returns the value the bar is expected to have reached after the given
+// time elapsed since the bar started. The expected position is rendered on the bar with the Pace character,
+// showing whether the progress is ahead or behind the expected pace.
+func (b *Bar) SetExpected(f func(elapsed time.Duration) int) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.expected = f
+	b.dirty = true
+	return b
+}
+
+// expectedWidth returns the number of cells up to the expected position, or 0 when no expectation is set
+func (b *Bar) expectedWidth(width int) int {
+	b.mtx.RLock()
+	expected, started, now, total := b.expected, b.TimeStarted, b.now, b.Total
+	b.mtx.RUnlock()
+	if expected == nil || total <= 0 {
+		return 0
+	}
+	var elapsed time.Duration
+	if !started.IsZero() {
+		elapsed = since(now(), started)
+	}
+	n := expected(elapsed)
+	if n <= 0 {
+		return 0
+	}
+	if n > total {
+		n = total
+	}
+	w, _ := mulDiv(n, width, total)
+	return w
+}
+
+// minWidth is the smallest width of a bar sized relative to the terminal or filling the line
+const minWidth = 3
+
+// width returns the width to render the bar with, see WidthPercent
+func (b *Bar) width() int {
+	b.mtx.RLock()
+	width, pct := b.Width, b.WidthPercent
+	b.mtx.RUnlock()
+	if pct <= 0 {
+		return width
+	}
+	cols, ok := terminalWidth()
+	if !ok {
+		return width
+	}
+	w := cols * pct / 100
+	if w < minWidth {
+		w = minWidth
+	}
+	return w
+}
+
+// indeterminateSize is the number of cells in the segment that bounces across a bar with an unknown total
+const indeterminateSize = 3
+
+// indeterminate appends a segment of fill that moves back and forth across the bar on every call to cells
+func (b *Bar) indeterminate(cells []rune, width int) []rune {
+	b.mtx.Lock()
+	frame := b.frame
+	b.frame++
+	b.mtx.Unlock()
+
+	start := len(cells)
+	for i := 0; i < width; i++ {
+		cells = append(cells, glyph(b.EmptyRune, b.Empty))
+	}
+	pb := cells[start:]
+	inner := width - 2
+	size := indeterminateSize
+	if size > inner {
+		size = inner
+	}
+	if size <= 0 {
+		return cells
+	}
+	pos := 0
+	if span := inner - size; span > 0 {
+		pos = frame % (2 * span)
+		if pos > span {
+			pos = 2*span - pos
+		}
+	}
+	for i := 0; i < size; i++ {
+		pb[1+pos+i] = glyph(b.FillRune, b.Fill)
+	}
+	return cells
+}
+
+// glyph returns r, or c when r is not set
+func glyph(r rune, c byte) rune {
+	if r != 0 {
+		return r
+	}
+	return rune(c)
+}
+
+// String returns the string representation of the bar
+func (b *Bar) String() string {
+	return string(b.Bytes())
+}
+
+// Report returns a static plain text snapshot of the bar for logs and reports, such as
+// "[=====-----] 50% (5/10)". Unlike String, it uses fixed ASCII characters, no head and no decorators. When the
+// total is unknown, the bar is empty and only the current value is shown, such as "[----------] (5)".
+func (b *Bar) Report() string {
+	b.mtx.RLock()
+	width := b.Width - 2
+	b.mtx.RUnlock()
+	if width < 1 {
+		width = 1
+	}
+	current, total := b.state()
+	pct, filled := 100.0, width
+	if total != 0 {
+		pct = percent(current, total)
+	}
+	if total < 0 {
+		filled = 0
+	} else if total > 0 && current < total {
+		filled, _ = mulDiv(current, width, total)
+	}
+	bar := "[" + strings.Repeat("=", filled) + strings.Repeat("-", width-filled) + "]"
+	if total < 0 {
+		return fmt.Sprintf("%s (%s)", bar, b.UnitFormatter(current))
+	}
+	return fmt.Sprintf("%s %.f%% (%s/%s)", bar, pct, b.UnitFormatter(current), b.UnitFormatter(total))
+}
+
+// RenderedWidth returns the number of columns taken by the bar and its decorators when rendered, counting
+// runes rather than bytes and ignoring ANSI escape sequences such as colors
+func (b *Bar) RenderedWidth() int {
+	return displayWidth(b.Bytes())
+}
+
+// displayWidth returns the number of runes in p outside of ANSI CSI and OSC escape sequences
+func displayWidth(p []byte) int {
+	var n int
+	for i := 0; i < len(p); {
+		if p[i] == '\x1b' {
+			i += strutil.EscapeLen(p[i:])
+			continue
+		}
+		_, size := utf8.DecodeRune(p[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// BytesWidth is like Bytes with the output truncated to at most maxCols columns, see RenderedWidth. A
+// truncated bar ends with an ellipsis. Escape sequences are never split, and those past the cut are kept so
+// that colors are still reset.
+func (b *Bar) BytesWidth(maxCols int) []byte {
+	return truncateWidth(b.Bytes(), maxCols)
+}
+
+// ellipsis marks output truncated by truncateWidth
+const ellipsis = "…"
+
+// truncateWidth truncates p to max columns, ending it with an ellipsis when truncated
+func truncateWidth(p []byte, max int) []byte {
+	if displayWidth(p) <= max {
+		return p
+	}
+	if max <= 0 {
+		return nil
+	}
+	out := make([]byte, 0, len(p))
+	var n int
+	for i := 0; i < len(p); {
+		if p[i] == '\x1b' {
+			l := strutil.EscapeLen(p[i:])
+			out = append(out, p[i:i+l]...)
+			i += l
+			continue
+		}
+		_, size := utf8.DecodeRune(p[i:])
+		if n < max-1 {
+			out = append(out, p[i:i+size]...)
+		} else if n == max-1 {
+			out = append(out, ellipsis...)
+		}
+		i += size
+		n++
+	}
+	return out
+}
+
+// TotalUnknown returns true when the bar's total is negative, see UnknownTotal
+func (b *Bar) TotalUnknown() bool {
+	_, total := b.state()
+	return total < 0
+}
+
+// CompletedPercent return the percent completed. It returns 0 when the total is unknown.
+func (b *Bar) CompletedPercent() float64 {
+	return percent(b.state())
+}
+
+// percent returns current as a percent of total, or 0 when the total is unknown
+func percent(current, total int) float64 {
+	if total < 0 {
+		return 0
+	}
+	if total == 0 {
+		return (float64(current) / float64(total)) * 100.00
+	}
+	// the whole multiples of total and the rest are scaled apart, keeping the math exact above 2^53
+	whole, rest := current/total, current%total
+	pct, rem := mulDiv(rest, 100, total)
+	return float64(whole)*100 + float64(pct) + float64(rem)/float64(total)
+}
+
+// mulDiv returns n*m/d and its remainder, multiplying in 128 bits so the product cannot overflow. n and m
+// must not be negative, and n must not exceed d, which must be positive.
+func mulDiv(n, m, d int) (q, r int) {
+	hi, lo := bits.Mul64(uint64(n), uint64(m))
+	uq, ur := bits.Div64(hi, lo, uint64(d))
+	return int(uq), int(ur)
+}
+
+// CompletedPercentString returns the formatted string representation of the completed percent. When the
+// total is unknown, it returns the formatted current value instead.
+func (b *Bar) CompletedPercentString() string {
+	if b.TotalUnknown() {
+		return b.FormattedCurrent()
+	}
+	pct := b.CompletedPercent()
+	if b.isCountdown() {
+		pct = 100 - pct
+	}
+	return sprintf(b.NumberPrinter, "%3.f%%", pct)
+}
+
+// RateString returns the formatted string representation of the rate, e.g. "4.20MiB/s"
+func (b *Bar) RateString() string {
+	return NewRateFormatter(b.UnitFormatter)(b.Rate())
+}
+
+// TimeElapsed returns the time elapsed
+func (b *Bar) TimeElapsed() time.Duration {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.timeElapsed
+}
+
+// ElapsedSinceStart returns the time elapsed since the bar started, computed from the clock of the bar on
+// every call, so unlike TimeElapsed, which is updated with the progress, it keeps ticking while the bar is
+// stalled. Once the bar is completed or failed, it returns TimeElapsed. It returns 0 before the bar starts.
+func (b *Bar) ElapsedSinceStart() time.Duration {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	if b.TimeStarted.IsZero() || b.err != nil || (b.Total >= 0 && b.current >= b.Total) {
+		return b.timeElapsed
+	}
+	if elapsed := b.elapsedBase + since(b.now(), b.TimeStarted); elapsed > b.timeElapsed {
+		return elapsed
+	}
+	return b.timeElapsed
+}
+
+// TimeElapsedString returns the formatted string represenation of the time elapsed
+func (b *Bar) TimeElapsedString() string {
+	return b.TimeFormatter(b.TimeElapsed())
+}
+
+// TimeRemaining returns the estimated time until the bar completes, which is what is left of the duration of
+// a bar set with AutoAdvance, or the estimate of the Estimator otherwise, see SetEstimator. It returns 0 once
+// the bar is completed, or when there is no rate to estimate from.
+func (b *Bar) TimeRemaining() time.Duration {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	if b.Total < 0 || b.current >= b.Total {
+		return 0
+	}
+	if b.autoFor > 0 && b.err == nil {
+		if b.autoFrom.IsZero() {
+			return b.autoFor
+		}
+		if left := b.autoFor - since(b.now(), b.autoFrom); left > 0 {
+			return left
+		}
+		return 0
+	}
+	if b.estimator != nil {
+		return b.estimator.Remaining(b.current, b.Total)
+	}
+	return remaining(b.Total-b.current, float64(b.current)/b.timeElapsed.Seconds())
+}
+
+// TimeRemainingString returns the formatted string represenation of the time remaining, see TimeRemaining
+func (b *Bar) TimeRemainingString() string {
+	return b.TimeFormatter(b.TimeRemaining())
+}
+
+// ReadUpdater wraps input so that every read advances the bar by the number of bytes read. When input
+// also implements io.Closer or io.Seeker, the returned reader forwards Close and Seek to input.
+func (b *Bar) ReadUpdater(input io.Reader, opts ...ReaderOption) io.Reader {
+	return b.readUpdater(input, opts, false)
+}
+
+func (b *Bar) readUpdater(input io.Reader, opts []ReaderOption, shared bool) io.Reader {
+	p := &ReadProgressor{
+		readerConfig: newReaderConfig(opts),
+		bar:          b,
+		input:        input,
+		shared:       shared,
+	}
+	c, closer := input.(io.Closer)
+	s, seeker := input.(io.Seeker)
+	switch {
+	case closer && seeker:
+		return &ReadSeekCloseProgressor{ReadCloseProgressor: &ReadCloseProgressor{ReadProgressor: p, closer: c}, seeker: s}
+	case closer:
+		return &ReadCloseProgressor{ReadProgressor: p, closer: c}
+	case seeker:
+		return &ReadSeekProgressor{ReadProgressor: p, seeker: s}
+	}
+	return p
+}
+
+// SharedReadUpdater is like ReadUpdater for readers that each deliver a part of the data tracked by the
+// bar, such as the segments of a parallel download. Any number of readers returned by SharedReadUpdater may
+// feed the same bar concurrently. Reaching EOF on one of them leaves the bar as is, even with CompleteOnEOF
+// or an unknown total.
+func (b *Bar) SharedReadUpdater(input io.Reader, opts ...ReaderOption) io.Reader {
+	return b.readUpdater(input, opts, true)
+}
+
+// FormattedCurrent returns the current value formatted with the PairFormatter when set, or else the
+// UnitFormatter
 func (b *Bar) FormattedCurrent() string {
-	return b.UnitFormatter(b.Current())
+	current, _ := b.formatted()
+	return current
 }
+
+// FormattedTotal returns the total value formatted with the PairFormatter when set, or else the
+// UnitFormatter
 func (b *Bar) FormattedTotal() string {
-	return b.UnitFormatter(b.Total)
+	_, total := b.formatted()
+	return total
+}
+
+// Remaining returns the amount left to reach the total, which is never negative. It returns 0 when the total
+// is unknown.
+func (b *Bar) Remaining() int {
+	current, total := b.state()
+	if total < 0 || current >= total {
+		return 0
+	}
+	return total - current
+}
+
+// FormattedRemaining returns the remaining amount formatted with the UnitFormatter, see Remaining
+func (b *Bar) FormattedRemaining() string {
+	return b.UnitFormatter(b.Remaining())
+}
+
+// formatted returns the current and the total values formatted, see FormattedCurrent
+func (b *Bar) formatted() (string, string) {
+	current, total := b.state()
+	if b.PairFormatter != nil {
+		return b.PairFormatter(current, total)
+	}
+	return b.UnitFormatter(current), b.UnitFormatter(total)
+}
+
+// ReaderOption configures the readers returned by ReadUpdater and the other I/O wrappers
+type ReaderOption func(*readerConfig)
+
+// readerConfig holds the options shared by the I/O wrappers
+type readerConfig struct {
+	completeOnClose  bool
+	completeOnEOF    bool
+	clamp            bool
+	dedup            bool
+	throttleBytes    int
+	throttleInterval time.Duration
+	onChunk          []func(p []byte, n int)
 }
 
+// chunk runs the OnChunk callbacks for the bytes in p
+func (c *readerConfig) chunk(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	for _, f := range c.onChunk {
+		f(p, len(p))
+	}
+}
+
+func newReaderConfig(opts []ReaderOption) readerConfig {
+	var c readerConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// CompleteOnClose sets the bar to its total when the reader is closed before reaching EOF. By default
+// the bar is left where it is.
+func CompleteOnClose() ReaderOption {
+	return func(c *readerConfig) {
+		c.completeOnClose = true
+	}
+}
+
+// CompleteOnEOF sets the bar to its total when the wrapped reader returns io.EOF, even if fewer bytes than
+// the total were read. By default the bar only advances by the bytes actually read.
+func CompleteOnEOF() ReaderOption {
+	return func(c *readerConfig) {
+		c.completeOnEOF = true
+	}
+}
+
+// Clamp stops the bar at its total when more bytes than the total are read, whatever the Overflow mode of
+// the bar
+func Clamp() ReaderOption {
+	return func(c *readerConfig) {
+		c.clamp = true
+	}
+}
+
+// OnChunk registers f to be called with the bytes of every successful read or write, before the bar is
+// advanced. Callbacks run synchronously in the order registered and must not retain p. A panicking callback
+// propagates to the caller of Read or Write and leaves the bar as it was before the call.
+func OnChunk(f func(p []byte, n int)) ReaderOption {
+	return func(c *readerConfig) {
+		c.onChunk = append(c.onChunk, f)
+	}
+}
+
+// DefaultThrottleBytes and DefaultThrottleInterval are the limits used by Throttle when given zero values
+const (
+	DefaultThrottleBytes    = 64 * 1024
+	DefaultThrottleInterval = 50 * time.Millisecond
+)
+
+// Throttle coalesces the updates to the bar, advancing it only once n bytes have accumulated or d has passed
+// since the last update, as measured by the clock of the bar. The remaining bytes are always added on EOF, on
+// error and on Close, so the final count is exact. A zero n or d uses DefaultThrottleBytes or
+// DefaultThrottleInterval.
+func Throttle(n int, d time.Duration) ReaderOption {
+	if n <= 0 {
+		n = DefaultThrottleBytes
+	}
+	if d <= 0 {
+		d = DefaultThrottleInterval
+	}
+	return func(c *readerConfig) {
+		c.throttleBytes, c.throttleInterval = n, d
+	}
+}
+
+// pending holds the bytes transferred by an I/O wrapper that are not yet added to the bar, see Throttle
+type pending struct {
+	n    int
+	last time.Time
+}
+
+// add adds n bytes, advancing bar once the limits of c are reached, or right away when not throttled
+func (p *pending) add(bar *Bar, c *readerConfig, n int) error {
+	p.n += n
+	if c.throttleBytes > 0 {
+		now := bar.clockNow()
+		if p.last.IsZero() {
+			p.last = now
+		}
+		if p.n < c.throttleBytes && since(now, p.last) < c.throttleInterval {
+			return nil
+		}
+		p.last = now
+	}
+	return p.flush(bar, c.clamp)
+}
+
+// flush advances bar by the pending bytes
+func (p *pending) flush(bar *Bar, clamp bool) error {
+	n := p.n
+	p.n = 0
+	return bar.count(n, clamp)
+}
+
+// ReadProgressor is an io.Reader that advances a bar as data is read
 type ReadProgressor struct {
-	bar   *Bar
-	input io.Reader
+	readerConfig
+
+	bar     *Bar
+	input   io.Reader
+	eof     bool
+	shared  bool
+	pending pending
 }
 
 func (p *ReadProgressor) Read(into []byte) (int, error) {
 	amt, err := p.input.Read(into)
+	p.chunk(into[:amt])
 	if err == io.EOF {
-		p.bar.Set(p.bar.Total)
-		return amt, err
-	} else if err != nil {
+		// bytes beyond the total fail the final read like any other under OverflowFail
+		p.pending.n += amt
+		if cerr := p.pending.flush(p.bar, p.clamp); cerr != nil {
+			return amt, cerr
+		}
+		p.done()
 		return amt, err
 	}
+	if cerr := p.count(amt); cerr != nil {
+		return amt, cerr
+	}
+	if err != nil {
+		if cerr := p.flush(); cerr != nil {
+			return amt, cerr
+		}
+	}
+	return amt, err
+}
+
+// WriteTo implements io.WriterTo. When the wrapped reader implements io.WriterTo, the copy is delegated to
+// it and the bar advances as the data is written to w.
+func (p *ReadProgressor) WriteTo(w io.Writer) (int64, error) {
+	wt, ok := p.input.(io.WriterTo)
+	if !ok {
+		// hide WriteTo so io.Copy does not call back into this method
+		return io.Copy(w, struct{ io.Reader }{p})
+	}
+	n, err := wt.WriteTo(&progressWriter{w: w, p: p})
+	if ferr := p.flush(); ferr != nil && err == nil {
+		err = ferr
+	}
+	if err == nil {
+		p.done()
+	}
+	return n, err
+}
 
-	err = p.bar.Set(p.bar.Current() + amt)
+// count advances the bar by n bytes
+func (p *ReadProgressor) count(n int) error {
+	return p.pending.add(p.bar, &p.readerConfig, n)
+}
+
+// flush advances the bar by the bytes held back by Throttle
+func (p *ReadProgressor) flush() error {
+	return p.pending.flush(p.bar, p.clamp)
+}
+
+// done updates the bar once the wrapped reader reached EOF
+func (p *ReadProgressor) done() {
+	p.eof = true
+	if p.shared {
+		return
+	}
+	p.bar.resolveTotal()
+	if p.completeOnEOF {
+		p.bar.Finish()
+	}
+}
+
+// progressWriter advances the bar of a ReadProgressor as data is written to w
+type progressWriter struct {
+	w io.Writer
+	p *ReadProgressor
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.p.chunk(b[:n])
+	if cerr := pw.p.count(n); cerr != nil && err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// ReadCloseProgressor is a ReadProgressor that forwards Close to the wrapped reader
+type ReadCloseProgressor struct {
+	*ReadProgressor
+
+	closer io.Closer
+	once   sync.Once
+	err    error
+}
+
+// Close closes the wrapped reader. The underlying Close is called only once; subsequent calls return
+// the result of the first call.
+func (p *ReadCloseProgressor) Close() error {
+	p.once.Do(func() {
+		p.flush()
+		if p.completeOnClose && !p.eof {
+			p.bar.Finish()
+		}
+		p.err = p.closer.Close()
+	})
+	return p.err
+}
+
+// seek seeks s and moves the bar to the new offset
+func (p *ReadProgressor) seek(s io.Seeker, offset int64, whence int) (int64, error) {
+	pos, err := s.Seek(offset, whence)
 	if err != nil {
-		return amt, fmt.Errorf("progress bar failure: %s", err)
+		return pos, err
+	}
+	p.eof = false
+	p.pending.n = 0
+	if !p.shared {
+		p.bar.set(int(pos), true)
 	}
-	return amt, nil
+	return pos, nil
+}
+
+// ReadSeekProgressor is a ReadProgressor that forwards Seek to the wrapped reader
+type ReadSeekProgressor struct {
+	*ReadProgressor
+
+	seeker io.Seeker
+}
+
+// Seek seeks the wrapped reader and sets the bar's current value to the new offset
+func (p *ReadSeekProgressor) Seek(offset int64, whence int) (int64, error) {
+	return p.seek(p.seeker, offset, whence)
+}
+
+// ReadSeekCloseProgressor is a ReadProgressor that forwards Seek and Close to the wrapped reader
+type ReadSeekCloseProgressor struct {
+	*ReadCloseProgressor
+
+	seeker io.Seeker
+}
+
+// Seek seeks the wrapped reader and sets the bar's current value to the new offset
+func (p *ReadSeekCloseProgressor) Seek(offset int64, whence int) (int64, error) {
+	return p.seek(p.seeker, offset, whence)
 }
 
 func DefaultFormatter(val int) string {
+	if DefaultNumberPrinter != nil {
+		return DefaultNumberPrinter.Sprintf("%d", val)
+	}
 	return strconv.Itoa(val)
 }
 
+// NumberPrinter formats numbers for display, such as for a locale. It is satisfied by the Printer of
+// golang.org/x/text/message.
+type NumberPrinter interface {
+	Sprintf(format string, a ...interface{}) string
+}
+
+// DefaultNumberPrinter formats the numbers written by the built-in formatters and by bars without a
+// NumberPrinter. When nil, numbers are formatted with the fmt package.
+var DefaultNumberPrinter NumberPrinter
+
+// sprintf formats with p, falling back to DefaultNumberPrinter and then to fmt.Sprintf
+func sprintf(p NumberPrinter, format string, a ...interface{}) string {
+	if p == nil {
+		p = DefaultNumberPrinter
+	}
+	if p == nil {
+		return fmt.Sprintf(format, a...)
+	}
+	return p.Sprintf(format, a...)
+}
+
+// BytesFormatter formats the value as bytes with binary units and two decimals, e.g. "1.50MiB"
 func BytesFormatter(val int) string {
+	return defaultBytesFormatter(val)
+}
+
+var defaultBytesFormatter = NewBytesFormatter()
+
+// binaryUnits are the units of the bytes formatters above bytes, see BytesDecimal for the decimal ones
+var binaryUnits = []string{"KiB", "MiB", "GiB", "TiB"}
+
+// BytesOption configures the formatters returned by NewBytesFormatter
+type BytesOption func(*bytesFormat)
+
+// bytesFormat holds the options of a bytes formatter
+type bytesFormat struct {
+	precision int
+	base      float64
+	units     []string
+	space     bool
+	threshold float64
+	printer   NumberPrinter
+}
+
+// BytesPrecision sets the number of decimals of the values above bytes. Defaults to 2.
+func BytesPrecision(n int) BytesOption {
+	return func(f *bytesFormat) {
+		if n >= 0 {
+			f.precision = n
+		}
+	}
+}
+
+// BytesDecimal uses decimal SI units, where 1 kB is 1000 bytes, instead of binary units
+func BytesDecimal() BytesOption {
+	return func(f *bytesFormat) {
+		f.base, f.units = 1000, siUnits
+	}
+}
+
+// BytesSpace separates the value from the unit with a space, e.g. "1.50 MiB"
+func BytesSpace() BytesOption {
+	return func(f *bytesFormat) {
+		f.space = true
+	}
+}
+
+// BytesThreshold sets the fraction of the next unit at which the value switches to that unit. With 0.5,
+// 920KiB is formatted as "0.90MiB". Defaults to 1.
+func BytesThreshold(t float64) BytesOption {
+	return func(f *bytesFormat) {
+		if t > 0 {
+			f.threshold = t
+		}
+	}
+}
+
+// BytesNumberPrinter formats the numbers with p rather than DefaultNumberPrinter
+func BytesNumberPrinter(p NumberPrinter) BytesOption {
+	return func(f *bytesFormat) {
+		f.printer = p
+	}
+}
+
+// NewBytesFormatter returns a formatter of bytes configured with opts. Without options it formats values
+// like BytesFormatter. Negative values are formatted as "0".
+func NewBytesFormatter(opts ...BytesOption) UnitFormatter {
+	return newBytesFormat(opts).format
+}
+
+func newBytesFormat(opts []BytesOption) bytesFormat {
+	f := bytesFormat{precision: 2, base: 1024, units: binaryUnits, threshold: 1}
+	for _, opt := range opts {
+		opt(&f)
+	}
+	return f
+}
+
+func (f bytesFormat) format(val int) string {
+	return f.formatIn(val, f.unit(val))
+}
+
+// unit returns the index in units of the unit val is written in, or -1 for bytes
+func (f bytesFormat) unit(val int) int {
+	v, i := float64(val), -1
+	for i < len(f.units)-1 && v >= f.base*f.threshold {
+		v /= f.base
+		i++
+	}
+	// move to the next unit when rounding would print the base of the current one, such as 1024.00KiB
+	scale := math.Pow(10, float64(f.precision))
+	for i >= 0 && i < len(f.units)-1 && math.Round(v*scale)/scale >= f.base {
+		v /= f.base
+		i++
+	}
+	return i
+}
+
+// formatIn formats val in the unit at index i of units, or in bytes when i is -1
+func (f bytesFormat) formatIn(val, i int) string {
 	if val < 0 {
 		return "0"
 	}
+	var sep string
+	if f.space {
+		sep = " "
+	}
+	if i < 0 {
+		return sprintf(f.printer, "%d%sB", val, sep)
+	}
+	return sprintf(f.printer, "%.*f%s%s", f.precision, float64(val)/math.Pow(f.base, float64(i+1)), sep, f.units[i])
+}
+
+// PairFormatter formats the current and the total values of a bar together, so that both can be written
+// consistently, such as in the same unit. When set on a bar, it is used instead of the UnitFormatter.
+type PairFormatter func(current, total int) (string, string)
+
+// BytesPairFormatter formats the current and the total values like BytesFormatter, both in the unit of the
+// total, e.g. "0.00GiB" and "1.20GiB". When the total is unknown, each value is formatted in its own unit.
+func BytesPairFormatter(current, total int) (string, string) {
+	return defaultBytesPairFormatter(current, total)
+}
+
+var defaultBytesPairFormatter = NewBytesPairFormatter()
+
+// NewBytesPairFormatter returns a formatter like BytesPairFormatter configured with opts, see
+// NewBytesFormatter
+func NewBytesPairFormatter(opts ...BytesOption) PairFormatter {
+	f := newBytesFormat(opts)
+	return func(current, total int) (string, string) {
+		if total < 0 {
+			return f.format(current), f.format(total)
+		}
+		i := f.unit(total)
+		return f.formatIn(current, i), f.formatIn(total, i)
+	}
+}
+
+// BytesFormatterFixed returns a formatter like BytesFormatter that pads the result on the left to width
+// characters, so the decorators to the right of it do not move as the value changes units. Values whose
+// formatted length exceeds width are not truncated; a width of 10 fits every value below 1024TiB.
+func BytesFormatterFixed(width int) UnitFormatter {
+	return func(val int) string {
+		return strutil.PadLeft(BytesFormatter(val), width, ' ')
+	}
+}
+
+// siUnits are the units of SIBytesFormatter above bytes
+var siUnits = []string{"kB", "MB", "GB", "TB"}
+
+// SIBytesFormatter formats the value as bytes with decimal SI units, where 1 kB is 1000 bytes, as shown by
+// browsers and network tools, e.g. "1.50MB". Negative values are formatted as "0B".
+func SIBytesFormatter(val int) string {
+	if val < 1000 {
+		if val < 0 {
+			val = 0
+		}
+		return sprintf(nil, "%dB", val)
+	}
+	f := float64(val) / 1000
+	i := 0
+	// move to the next unit when rounding would print 1000 of the current one
+	for i < len(siUnits)-1 && math.Round(f*100)/100 >= 1000 {
+		f /= 1000
+		i++
+	}
+	return sprintf(nil, "%.2f%s", f, siUnits[i])
+}
+
+// DurationFormatter formats the value as a number of seconds, e.g. "1h2m5s" for 3725, using
+// strutil.PrettyTime like the default TimeFormatter. Zero and negative values are formatted as "0s".
+func DurationFormatter(val int) string {
+	return formatDuration(time.Duration(val) * time.Second)
+}
+
+// DurationFormatterMillis is like DurationFormatter for values in milliseconds
+func DurationFormatterMillis(val int) string {
+	return formatDuration(time.Duration(val) * time.Millisecond)
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return "0s"
+	}
+	return strutil.PrettyTime(d)
+}
+
+// countUnits are the short scale abbreviations of HumanCountFormatter
+var countUnits = []string{"k", "M", "B", "T", "Q", "Qi"}
+
+// HumanCountFormatter formats the value as a count abbreviated with the short scale and one decimal, e.g.
+// "999", "1.0k", "12.5k", "3.4M" or "5.6B". The result is at most 6 characters long, see
+// PaddedHumanCountFormatter for a fixed width. Negative values are formatted as "0".
+func HumanCountFormatter(val int) string {
+	if val < 1000 {
+		if val < 0 {
+			val = 0
+		}
+		return strconv.Itoa(val)
+	}
+	f := float64(val) / 1000
+	i := 0
+	// move to the next unit when rounding would print 1000 of the current one
+	for i < len(countUnits)-1 && math.Round(f*10)/10 >= 1000 {
+		f /= 1000
+		i++
+	}
+	return sprintf(nil, "%.1f%s", f, countUnits[i])
+}
+
+// PaddedHumanCountFormatter is like HumanCountFormatter with the result padded on the left to 6 characters,
+// so decorators using it keep the same width as the value changes
+func PaddedHumanCountFormatter(val int) string {
+	return strutil.PadLeft(HumanCountFormatter(val), 6, ' ')
+}
+
+// NewSuffixFormatter returns a formatter that writes the value followed by a space and suffix, such as
+// "1,204 files", with the thousands grouped by commas. When humanize is set, the value is abbreviated by
+// HumanCountFormatter instead, such as "1.2k files". The suffix is used verbatim for every value. Negative
+// values are formatted as 0.
+func NewSuffixFormatter(suffix string, humanize bool) UnitFormatter {
+	return func(val int) string {
+		if humanize {
+			return HumanCountFormatter(val) + " " + suffix
+		}
+		return groupThousands(val) + " " + suffix
+	}
+}
+
+// groupThousands formats val with its thousands separated by commas. Negative values are formatted as "0".
+func groupThousands(val int) string {
+	if val < 0 {
+		val = 0
+	}
+	return grouped(val, comma)
+}
+
+var comma = []byte{','}
+
+// GroupedFormatter formats the value with its thousands separated by commas, e.g. "12,493,021"
+func GroupedFormatter(val int) string {
+	return grouped(val, comma)
+}
+
+// NewGroupedFormatter returns a formatter like GroupedFormatter that separates the thousands with sep, such
+// as '.' or a thin space. An invalid sep is written as utf8.RuneError
+func NewGroupedFormatter(sep rune) UnitFormatter {
+	b := utf8.AppendRune(nil, sep)
+	return func(val int) string {
+		return grouped(val, b)
+	}
+}
+
+// grouped formats val with its thousands separated by sep, allocating only the result
+func grouped(val int, sep []byte) string {
+	var digits, out [64]byte
+	d := strconv.AppendInt(digits[:0], int64(val), 10)
+	buf := out[:0]
+	if d[0] == '-' {
+		buf = append(buf, '-')
+		d = d[1:]
+	}
+	for i, c := range d {
+		if i > 0 && (len(d)-i)%3 == 0 {
+			buf = append(buf, sep...)
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
 
-	lg2 := math.Log2(float64(val))
-	magn := uint(lg2 / 10.0)
-	switch magn {
-	case 0:
-		return fmt.Sprintf("%dB", val)
-	case 1:
-		return fmt.Sprintf("%.2fKiB", float64(val)/float64(1024))
-	case 2:
-		return fmt.Sprintf("%.2fMiB", float64(val)/float64(1024*1024))
-	case 3:
-		return fmt.Sprintf("%.2fGiB", float64(val)/float64(1024*1024*1024))
-	default:
-		return fmt.Sprintf("%.2fTiB", float64(val)/float64(1024*1024*1024*1024))
+// NewRateFormatter returns a function that formats a rate per second with base followed by "/s", such as
+// "3.20MiB/s" with BytesFormatter or "1.2k/s" with HumanCountFormatter. Rates below 1 are written with one
+// decimal and the unit of base, such as "0.4B/s". Negative rates are formatted as 0.
+func NewRateFormatter(base UnitFormatter) func(perSecond float64) string {
+	return func(perSecond float64) string {
+		if perSecond >= 1 {
+			return base(int(perSecond)) + "/s"
+		}
+		if perSecond <= 0 {
+			return base(0) + "/s"
+		}
+		// the unit of base is whatever it writes after the value for 0
+		unit := strings.TrimPrefix(strings.TrimLeft(base(0), " "), "0")
+		return sprintf(nil, "%.1f", perSecond) + unit + "/s"
 	}
 }
@@ -1,12 +1,22 @@
 package uiprogress
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
 	"math/rand"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/gosuri/uiprogress/util/strutil"
+	"github.com/gosuri/uiprogress/util/testutil"
 )
 
 func TestBarPrepend(t *testing.T) {
@@ -35,3 +45,1240 @@ func TestBarIncr(t *testing.T) {
 		t.Fatal("need", 10000, "got", b.Current())
 	}
 }
+
+type closeCounter struct {
+	io.Reader
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestReadUpdaterClose(t *testing.T) {
+	b := NewBar(10)
+	src := &closeCounter{Reader: strings.NewReader("0123456789")}
+	r := b.ReadUpdater(src)
+	rc, ok := r.(io.ReadCloser)
+	if !ok {
+		t.Fatal("want", "io.ReadCloser", "got", r)
+	}
+	rc.Close()
+	rc.Close()
+	if src.closed != 1 {
+		t.Fatal("want", 1, "got", src.closed)
+	}
+	if b.Current() != 0 {
+		t.Fatal("want", 0, "got", b.Current())
+	}
+
+	b = NewBar(10)
+	rc = b.ReadUpdater(&closeCounter{Reader: strings.NewReader("01234")}, CompleteOnClose()).(io.ReadCloser)
+	rc.Close()
+	if b.Current() != 10 {
+		t.Fatal("want", 10, "got", b.Current())
+	}
+
+	if _, ok := NewBar(10).ReadUpdater(strings.NewReader("x")).(io.Closer); ok {
+		t.Fatal("want", "plain io.Reader", "got", "io.Closer")
+	}
+}
+
+// eofReader returns the remaining data together with io.EOF in a single call
+type eofReader struct {
+	data []byte
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	if len(r.data) == 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestReadUpdaterEOF(t *testing.T) {
+	b := NewBar(10)
+	if _, err := ioutil.ReadAll(b.ReadUpdater(&eofReader{data: []byte("0123456789")})); err != nil {
+		t.Fatal(err)
+	}
+	if b.Current() != 10 {
+		t.Fatal("want", 10, "got", b.Current())
+	}
+
+	// a truncated stream leaves the bar where it truthfully is
+	b = NewBar(10)
+	ioutil.ReadAll(b.ReadUpdater(&eofReader{data: []byte("0123")}))
+	if b.Current() != 4 {
+		t.Fatal("want", 4, "got", b.Current())
+	}
+
+	b = NewBar(10)
+	ioutil.ReadAll(b.ReadUpdater(&eofReader{data: []byte("0123")}, CompleteOnEOF()))
+	if b.Current() != 10 {
+		t.Fatal("want", 10, "got", b.Current())
+	}
+}
+
+func TestReadUpdaterUnknownTotal(t *testing.T) {
+	b := NewBar(UnknownTotal)
+	b.Width = 10
+	b.AppendCompleted()
+	first := b.String()
+	if first == b.String() {
+		t.Fatal("want", "animated bar", "got", first)
+	}
+
+	data := strings.Repeat("x", 1000)
+	n, err := io.Copy(ioutil.Discard, b.ReadUpdater(strings.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Current() != int(n) || b.Total != len(data) {
+		t.Fatal("want", len(data), "got", b.Current(), b.Total)
+	}
+	if !b.IsCompleted() {
+		t.Fatal("want", "completed bar", "got", b.String())
+	}
+	if !strings.HasSuffix(b.String(), "100%") {
+		t.Fatal("want", "100%", "got", b.String())
+	}
+}
+
+func TestBarSetDirty(t *testing.T) {
+	b := NewBar(10)
+	b.takeDirty()
+	if err := b.Set(0); err != nil {
+		t.Fatal(err)
+	}
+	if b.takeDirty() {
+		t.Fatal("want", "clean bar after Set(same)", "got", "dirty")
+	}
+	b.Set(10)
+	if !b.takeDirty() {
+		t.Fatal("want", "dirty bar after Set(Total)", "got", "clean")
+	}
+}
+
+func TestReadUpdaterSeek(t *testing.T) {
+	b := NewBar(100)
+	r := b.ReadUpdater(strings.NewReader(strings.Repeat("x", 100)))
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		t.Fatal("want", "io.ReadSeeker", "got", r)
+	}
+	buf := make([]byte, 10)
+	steps := []struct {
+		offset int64
+		whence int
+		want   int
+	}{
+		{50, io.SeekStart, 60},
+		{-30, io.SeekCurrent, 40},
+		{-5, io.SeekEnd, 100},
+		{0, io.SeekStart, 10},
+	}
+	for _, s := range steps {
+		if _, err := rs.Seek(s.offset, s.whence); err != nil {
+			t.Fatal(err)
+		}
+		io.ReadFull(rs, buf[:5])
+		rs.Read(buf[:5])
+		if b.Current() != s.want {
+			t.Fatal("want", s.want, "got", b.Current())
+		}
+	}
+}
+
+func TestBarExpected(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 12
+	b.Set(2)
+	b.SetExpected(func(time.Duration) int { return 5 })
+	if got, want := b.String(), "[>---|-----]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarHugeTotal(t *testing.T) {
+	if strconv.IntSize != 64 {
+		t.Skip("totals beyond float64 precision need 64-bit ints")
+	}
+	total := int(^uint(0) >> 1)
+	b := NewBar(total)
+	b.Width = 102
+	filled := func() int {
+		s := b.String()
+		return strings.Count(s, "=") + strings.Count(s, ">")
+	}
+	step := total / 1000
+	prevWidth, prevPct := 0, 0.0
+	for i := 1; i <= 1000; i++ {
+		b.Set(i * step)
+		w, pct := filled(), b.CompletedPercent()
+		if w < prevWidth || w > prevWidth+1 {
+			t.Fatal("want", "a width of", prevWidth, "or", prevWidth+1, "got", w)
+		}
+		if pct < prevPct {
+			t.Fatal("want", "at least", prevPct, "got", pct)
+		}
+		prevWidth, prevPct = w, pct
+	}
+	// one short of the total, the head is still in the bar
+	b.Set(total - 1)
+	if got := b.String(); !strings.Contains(got, ">") {
+		t.Fatal("want", "a head", "got", got)
+	}
+	b.Set(total)
+	if w, pct := filled(), b.CompletedPercent(); w != 100 || pct != 100 {
+		t.Fatal("want", 100, 100, "got", w, pct)
+	}
+}
+
+func TestBarNowFunc(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBar(100).SetNowFunc(func() time.Time { return now })
+	b.Set(10)
+	now = now.Add(5 * time.Second)
+	b.Set(60)
+	if b.TimeElapsed() != 5*time.Second {
+		t.Fatal("want", 5*time.Second, "got", b.TimeElapsed())
+	}
+	if b.Rate() != 12 {
+		t.Fatal("want", 12, "got", b.Rate())
+	}
+}
+
+func TestBarClockBackwards(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBar(100).SetNowFunc(func() time.Time { return now })
+	b.SetExpected(func(d time.Duration) int {
+		if d < 0 {
+			t.Fatal("want", "a positive duration", "got", d)
+		}
+		return 0
+	})
+	b.Set(10)
+	now = now.Add(10 * time.Second)
+	b.Set(20)
+	for _, step := range []time.Duration{-time.Hour, time.Second, -time.Minute} {
+		now = now.Add(step)
+		b.Add(10)
+		b.Bytes()
+		if got := b.TimeElapsed(); got != 10*time.Second {
+			t.Fatal("want", 10*time.Second, "got", got)
+		}
+		if got := b.TimeElapsedString(); strings.HasPrefix(got, "-") {
+			t.Fatal("want", "a positive duration", "got", got)
+		}
+		if rate := b.InstantRate(); rate < 0 {
+			t.Fatal("want", "a positive rate", "got", rate)
+		}
+	}
+}
+
+func TestReadUpdaterWriteTo(t *testing.T) {
+	data := strings.Repeat("x", 100000)
+	for _, src := range []io.Reader{strings.NewReader(data), &eofReader{data: []byte(data)}} {
+		b := NewBar(len(data))
+		var dst bytes.Buffer
+		n, err := io.Copy(&dst, b.ReadUpdater(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(data)) || dst.Len() != len(data) || b.Current() != len(data) {
+			t.Fatal("want", len(data), "got", n, dst.Len(), b.Current())
+		}
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 1<<20)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		io.Copy(ioutil.Discard, bytes.NewReader(data))
+	}
+}
+
+func BenchmarkCopyReadUpdater(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 1<<20)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		io.Copy(ioutil.Discard, NewBar(len(data)).ReadUpdater(bytes.NewReader(data)))
+	}
+}
+
+func TestBarWidthPercent(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 20
+	b.WidthPercent = 50
+	cols, ok := terminalWidth()
+	want := b.Width
+	if ok {
+		if want = cols / 2; want < minWidth {
+			want = minWidth
+		}
+	}
+	if got := len(b.String()); got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarFillWidth(t *testing.T) {
+	cols, ok := terminalWidth()
+	if !ok {
+		cols = Width
+	}
+	b := NewBar(10).PrependFunc(func(b *Bar) string { return "download" }).AppendCompleted()
+	b.Width = 0
+	b.Set(5)
+	if got := b.RenderedWidth(); got != cols {
+		t.Fatal("want", cols, "got", got)
+	}
+
+	// decorators wider than the line are truncated, keeping the smallest bar
+	b = NewBar(10).PrependFunc(func(b *Bar) string { return strings.Repeat("x", cols) })
+	b.Width = 0
+	if got := b.String(); displayWidth([]byte(got)) != cols || !strings.HasSuffix(got, ellipsis) {
+		t.Fatal("want", cols, "columns ending with", ellipsis, "got", got)
+	}
+}
+
+func TestBarBlankEmpty(t *testing.T) {
+	progress := New()
+	var buffer = &bytes.Buffer{}
+	progress.SetOut(buffer)
+	progress.Width = 12
+	b := progress.AddBar(10)
+	b.EmptyRune = ' '
+	b.Set(3)
+	want := "[=>        ]"
+	if got := b.String(); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	progress.print(true)
+	if got := buffer.String(); !strings.HasSuffix(got, want+"\n") {
+		t.Fatalf("want suffix %q, got %q", want+"\n", got)
+	}
+}
+
+func TestBarSecondary(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 12
+	b.Set(5)
+	b.SetSecondary(3)
+	if got, want := b.String(), "[##==>-----]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestReadUpdaterOverflowGrow(t *testing.T) {
+	data := strings.Repeat("x", 110)
+	for _, src := range []io.Reader{strings.NewReader(data), &eofReader{data: []byte(data)}} {
+		b := NewBar(100).AppendCompleted()
+		if _, err := ioutil.ReadAll(b.ReadUpdater(src)); err != nil {
+			t.Fatal(err)
+		}
+		if b.Current() != 110 || b.Total != 110 || !b.IsCompleted() {
+			t.Fatal("want", 110, "got", b.Current(), b.Total)
+		}
+		if !strings.HasSuffix(b.String(), "100%") {
+			t.Fatal("want", "100%", "got", b.String())
+		}
+	}
+
+	b := NewBar(100)
+	b.Overflow = OverflowClamp
+	if _, err := ioutil.ReadAll(b.ReadUpdater(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if b.Current() != 100 || b.Total != 100 {
+		t.Fatal("want", 100, "got", b.Current(), b.Total)
+	}
+}
+
+func TestReadUpdaterTotalTooSmall(t *testing.T) {
+	// a wrong size must not abort the transfer, whatever the mode but OverflowFail
+	for mode, want := range map[OverflowMode]int{OverflowGrow: 100, OverflowClamp: 1} {
+		b := NewBar(1)
+		b.Overflow = mode
+		n, err := io.Copy(ioutil.Discard, b.ReadUpdater(strings.NewReader(strings.Repeat("x", 100))))
+		if n != 100 || err != nil {
+			t.Fatal("want", 100, nil, "got", n, err)
+		}
+		if b.Current() != want || b.Total != want {
+			t.Fatal("want", want, "got", b.Current(), b.Total)
+		}
+	}
+}
+
+func TestReadUpdaterErrors(t *testing.T) {
+	b := NewBar(5)
+	b.Overflow = OverflowFail
+	_, err := ioutil.ReadAll(b.ReadUpdater(strings.NewReader("0123456789")))
+	if !errors.Is(err, ErrMaxCurrentReached) {
+		t.Fatal("want", ErrMaxCurrentReached, "got", err)
+	}
+	var perr *ProgressError
+	if !errors.As(err, &perr) {
+		t.Fatal("want", "*ProgressError", "got", err)
+	}
+	if perr.Current != 0 || perr.Attempted != 10 || perr.Total != 5 {
+		t.Fatal("want", "0 10 5", "got", perr.Current, perr.Attempted, perr.Total)
+	}
+
+	readErr := errors.New("read failed")
+	_, err = ioutil.ReadAll(NewBar(5).ReadUpdater(&errReader{err: readErr}))
+	if err != readErr || errors.As(err, &perr) {
+		t.Fatal("want", readErr, "got", err)
+	}
+}
+
+func TestReadUpdaterThrottle(t *testing.T) {
+	b := NewBar(10000)
+	r := b.ReadUpdater(strings.NewReader(strings.Repeat("x", 10000)), Throttle(4096, time.Hour))
+	buf := make([]byte, 1000)
+	r.Read(buf)
+	if b.Current() != 0 {
+		t.Fatal("want", 0, "got", b.Current())
+	}
+	for i := 0; i < 4; i++ {
+		r.Read(buf)
+	}
+	if b.Current() != 5000 {
+		t.Fatal("want", 5000, "got", b.Current())
+	}
+	ioutil.ReadAll(r)
+	if b.Current() != 10000 {
+		t.Fatal("want", 10000, "got", b.Current())
+	}
+}
+
+func TestReadUpdaterThrottleInterval(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(10000).SetClock(clock)
+	r := b.ReadUpdater(strings.NewReader(strings.Repeat("x", 10000)), Throttle(1<<20, time.Second))
+	buf := make([]byte, 1000)
+	r.Read(buf)
+	r.Read(buf)
+	if b.Current() != 0 {
+		t.Fatal("want", 0, "got", b.Current())
+	}
+	clock.Advance(time.Second)
+	r.Read(buf)
+	if b.Current() != 3000 {
+		t.Fatal("want", 3000, "got", b.Current())
+	}
+	r.Read(buf)
+	if b.Current() != 3000 {
+		t.Fatal("want", 3000, "got", b.Current())
+	}
+}
+
+// zeroReader reads an endless stream of zeros
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func benchmarkReadUpdater(b *testing.B, opts ...ReaderOption) {
+	const size = 1 << 30
+	b.SetBytes(size)
+	for i := 0; i < b.N; i++ {
+		bar := NewBar(size)
+		io.CopyBuffer(ioutil.Discard, struct{ io.Reader }{bar.ReadUpdater(io.LimitReader(zeroReader{}, size), opts...)}, make([]byte, 32*1024))
+	}
+}
+
+func BenchmarkReadUpdater(b *testing.B) {
+	benchmarkReadUpdater(b)
+}
+
+func BenchmarkReadUpdaterThrottle(b *testing.B) {
+	benchmarkReadUpdater(b, Throttle(0, 0))
+}
+
+func TestSharedReadUpdater(t *testing.T) {
+	const segments, size = 8, 10000
+	b := NewBar(UnknownTotal)
+	var wg sync.WaitGroup
+	for i := 0; i < segments; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := b.SharedReadUpdater(strings.NewReader(strings.Repeat("x", size)), CompleteOnEOF())
+			buf := make([]byte, 7)
+			for {
+				if _, err := r.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if b.Current() != segments*size || !b.TotalUnknown() {
+		t.Fatal("want", segments*size, "got", b.Current(), b.Total)
+	}
+}
+
+func TestReadUpdaterFinalChunk(t *testing.T) {
+	// the total was under-estimated, and the bytes beyond it arrive before io.EOF or together with it
+	for _, src := range []func() io.Reader{
+		func() io.Reader { return strings.NewReader("0123456789") },
+		func() io.Reader { return &eofReader{data: []byte("0123456789")} },
+	} {
+		b := NewBar(8)
+		b.Overflow = OverflowFail
+		if _, err := ioutil.ReadAll(b.ReadUpdater(src())); !errors.Is(err, ErrMaxCurrentReached) {
+			t.Fatal("want", ErrMaxCurrentReached, "got", err)
+		}
+		if b.Current() != 0 || b.IsCompleted() {
+			t.Fatal("want", 0, "got", b.Current())
+		}
+
+		// Clamp takes precedence over OverflowFail
+		b = NewBar(8)
+		b.Overflow = OverflowFail
+		data, err := ioutil.ReadAll(b.ReadUpdater(src(), Clamp()))
+		if err != nil || len(data) != 10 || b.Current() != 8 {
+			t.Fatal("want", 10, 8, "got", len(data), b.Current(), err)
+		}
+	}
+}
+
+func TestBarSeparators(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 5
+	b.AppendSep = " | "
+	b.PrependSep = ": "
+	b.AppendFunc(func(*Bar) string { return "a" })
+	b.AppendFunc(func(*Bar) string { return "" })
+	b.AppendFunc(func(*Bar) string { return "b" })
+	b.PrependFunc(func(*Bar) string { return "c" })
+	b.PrependFunc(func(*Bar) string { return "" })
+	if got, want := b.String(), "c: [---] | a | b"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarSetTotalRace(t *testing.T) {
+	b := NewBar(100)
+	b.AppendCompleted()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 100; i < 200; i++ {
+			b.SetTotal(i)
+			b.Incr()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = b.String() + b.FormattedTotal()
+		}
+	}()
+	wg.Wait()
+	if len(b.Bytes()) == 0 {
+		t.Fatal("want", "rendered bar", "got", "nothing")
+	}
+}
+
+func TestReadUpdaterOnChunk(t *testing.T) {
+	b := NewBar(10)
+	var got []string
+	r := b.ReadUpdater(strings.NewReader("0123456789"),
+		OnChunk(func(p []byte, n int) { got = append(got, "a"+string(p[:n])) }),
+		OnChunk(func(p []byte, n int) { got = append(got, "b"+string(p[:n])) }),
+	)
+	buf := make([]byte, 4)
+	r.Read(buf)
+	if strings.Join(got, ",") != "a0123,b0123" {
+		t.Fatal("want", "a0123,b0123", "got", got)
+	}
+
+	r = b.ReadUpdater(strings.NewReader("456789"), OnChunk(func([]byte, int) { panic("callback") }))
+	func() {
+		defer func() { recover() }()
+		r.Read(buf)
+	}()
+	if b.Current() != 4 {
+		t.Fatal("want", 4, "got", b.Current())
+	}
+	if err := b.Set(5); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBarAlwaysShowHead(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 10
+	b.AlwaysShowHead = true
+	if got, want := b.String(), "[>-------]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	b.Set(1)
+	if got, want := b.String(), "[>-------]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	b.Set(10)
+	if got, want := b.String(), "[========]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarRenderedWidth(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 10
+	b.PrependFunc(func(b *Bar) string { return "\x1b[32mdownloading ✓\x1b[0m" })
+	b.AppendFunc(func(b *Bar) string { return "\x1b]8;;http://x\x1b\\100%\x1b]8;;\x07" })
+	if got, want := b.RenderedWidth(), len("downloading x")+1+10+1+len("100%"); got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarRunes(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 10
+	b.LeftEndRune, b.RightEndRune = '│', '│'
+	b.FillRune, b.HeadRune = '█', '▓'
+	b.Set(5)
+	if got, want := b.String(), "│███▓----│"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	if got := b.RenderedWidth(); got != 10 {
+		t.Fatal("want", 10, "got", got)
+	}
+}
+
+func TestSIBytesFormatter(t *testing.T) {
+	for val, want := range map[int]string{
+		-5:            "0B",
+		0:             "0B",
+		999:           "999B",
+		1000:          "1.00kB",
+		1500:          "1.50kB",
+		999_999:       "1.00MB",
+		1_000_000:     "1.00MB",
+		1_500_000_000: "1.50GB",
+	} {
+		if got := SIBytesFormatter(val); got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+	if strconv.IntSize == 64 {
+		if got, want := SIBytesFormatter(int(^uint(0)>>1)), "9223372.04TB"; got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+}
+
+func TestDurationFormatter(t *testing.T) {
+	for val, want := range map[int]string{-1: "0s", 0: "0s", 59: "59s", 3725: "1h2m5s"} {
+		if got := DurationFormatter(val); got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+	for val, want := range map[int]string{-1: "0s", 999: "0s", 61500: "1m1s"} {
+		if got := DurationFormatterMillis(val); got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+}
+
+func TestThrottleDecorator(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(10).SetClock(clock)
+	b.Width = 3
+	var calls int
+	b.AppendFunc(ThrottleDecorator(func(b *Bar) string {
+		calls++
+		return strconv.Itoa(calls)
+	}, time.Second))
+	for _, step := range []struct {
+		advance time.Duration
+		want    string
+	}{{0, "[-] 1"}, {time.Millisecond * 500, "[-] 1"}, {time.Millisecond * 500, "[-] 2"}, {time.Millisecond, "[-] 2"}} {
+		clock.Advance(step.advance)
+		if got := b.String(); got != step.want {
+			t.Fatal("want", step.want, "got", got)
+		}
+	}
+}
+
+func TestHumanCountFormatter(t *testing.T) {
+	for val, want := range map[int]string{
+		-1:            "0",
+		0:             "0",
+		999:           "999",
+		1000:          "1.0k",
+		12_500:        "12.5k",
+		999_949:       "999.9k",
+		999_999:       "1.0M",
+		1_000_000:     "1.0M",
+		1_240_000_000: "1.2B",
+	} {
+		if got := HumanCountFormatter(val); got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+	if strconv.IntSize == 64 {
+		var billions int64 = 5_600_000_000
+		if got := HumanCountFormatter(int(billions)); got != "5.6B" {
+			t.Fatal("want", "5.6B", "got", got)
+		}
+		if got := HumanCountFormatter(int(^uint(0) >> 1)); len(got) > 6 {
+			t.Fatal("want", "at most 6 characters", "got", got)
+		}
+	}
+	if got := PaddedHumanCountFormatter(999); got != "   999" {
+		t.Fatal("want", "   999", "got", got)
+	}
+}
+
+func TestBarItems(t *testing.T) {
+	b := NewBar(1000).AppendItems().AppendRate()
+	b.Width = 3
+	b.UnitFormatter = BytesFormatter
+	b.SetItems(17, 50)
+	b.Set(500)
+	if got := b.String(); !strings.HasPrefix(got, "[-] 17/50 ") || !strings.HasSuffix(got, "/s") {
+		t.Fatal("want", "[-] 17/50 .../s", "got", got)
+	}
+	if b.Current() != 500 {
+		t.Fatal("want", 500, "got", b.Current())
+	}
+	b.SetItems(3, UnknownTotal)
+	if got := b.String(); !strings.HasPrefix(got, "[-] 3 ") {
+		t.Fatal("want", "[-] 3 ...", "got", got)
+	}
+}
+
+func TestBytesFormatter(t *testing.T) {
+	for val, want := range map[int]string{
+		-1:   "0",
+		0:    "0B",
+		1:    "1B",
+		1023: "1023B",
+		1024: "1.00KiB",
+		1536: "1.50KiB",
+		// 1023.995KiB and above round to 1024.00KiB
+		1048570: "1023.99KiB",
+		1048571: "1.00MiB",
+		1048575: "1.00MiB",
+	} {
+		if got := BytesFormatter(val); got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+}
+
+func TestBytesFormatterFixed(t *testing.T) {
+	format := BytesFormatterFixed(10)
+	for val := 1; val < 1<<30; val = val*3 + 1 {
+		if got := format(val); len(got) != 10 {
+			t.Fatalf("want 10 characters, got %q for %d", got, val)
+		}
+	}
+	if got := format(1048575); got != "   1.00MiB" {
+		t.Fatal("want", "   1.00MiB", "got", got)
+	}
+}
+
+func TestNewBytesFormatter(t *testing.T) {
+	for _, tc := range []struct {
+		format UnitFormatter
+		val    int
+		want   string
+	}{
+		{NewBytesFormatter(), 1536, "1.50KiB"},
+		{NewBytesFormatter(BytesPrecision(1), BytesSpace()), 1536, "1.5 KiB"},
+		{NewBytesFormatter(BytesDecimal()), 1500, "1.50kB"},
+		{NewBytesFormatter(BytesDecimal()), 999994, "999.99kB"},
+		{NewBytesFormatter(BytesDecimal()), 999995, "1.00MB"},
+		{NewBytesFormatter(BytesDecimal()), 999999, "1.00MB"},
+		{NewBytesFormatter(BytesDecimal(), BytesPrecision(0)), 999500, "1MB"},
+		{NewBytesFormatter(BytesThreshold(0.5)), 920 * 1024, "0.90MiB"},
+		{NewBytesFormatter(BytesThreshold(0.5)), 100, "100B"},
+	} {
+		if got := tc.format(tc.val); got != tc.want {
+			t.Fatal("want", tc.want, "got", got)
+		}
+	}
+}
+
+func TestNewBytesFormatterMonotonic(t *testing.T) {
+	parse := func(s string, base float64, units []string) float64 {
+		s = strings.Replace(s, " ", "", 1)
+		for i := len(units) - 1; i >= 0; i-- {
+			if strings.HasSuffix(s, units[i]) {
+				v, _ := strconv.ParseFloat(strings.TrimSuffix(s, units[i]), 64)
+				return v * math.Pow(base, float64(i+1))
+			}
+		}
+		v, _ := strconv.ParseFloat(strings.TrimSuffix(s, "B"), 64)
+		return v
+	}
+	for _, tc := range []struct {
+		opts  []BytesOption
+		base  float64
+		units []string
+	}{
+		{nil, 1024, binaryUnits},
+		{[]BytesOption{BytesDecimal(), BytesSpace(), BytesPrecision(1)}, 1000, siUnits},
+		{[]BytesOption{BytesThreshold(0.5), BytesPrecision(0)}, 1024, binaryUnits},
+	} {
+		format := NewBytesFormatter(tc.opts...)
+		var prev float64
+		r := rand.New(rand.NewSource(1))
+		for val := 0; val < 1<<30; val += 1 + r.Intn(val/50+1) {
+			got := parse(format(val), tc.base, tc.units)
+			if got < prev {
+				t.Fatalf("want at least %v, got %v for %d", prev, got, val)
+			}
+			prev = got
+		}
+	}
+}
+
+func TestBarSetCurrent(t *testing.T) {
+	b := NewBar(10)
+	if err := b.SetCurrent(4); err != nil || b.Current() != 4 {
+		t.Fatal("want", 4, "got", b.Current(), err)
+	}
+	if err := b.SetCurrent(11); !errors.Is(err, ErrMaxCurrentReached) || b.Current() != 4 {
+		t.Fatal("want", ErrMaxCurrentReached, "got", err, b.Current())
+	}
+	if err := b.SetCurrent(-3); err != nil || b.Current() != 0 {
+		t.Fatal("want", 0, "got", b.Current(), err)
+	}
+}
+
+func TestBarTimeFormatter(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(10).SetClock(clock).AppendElapsed()
+	b.Width = 3
+	b.TimeFormatter = func(d time.Duration) string {
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+	}
+	b.Incr()
+	clock.Advance(3*time.Minute + 45*time.Second)
+	b.Incr()
+	if got, want := b.String(), "[-] 00:03:45"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestNewSuffixFormatter(t *testing.T) {
+	format := NewSuffixFormatter("files", false)
+	for val, want := range map[int]string{-1: "0 files", 0: "0 files", 999: "999 files", 1204: "1,204 files", 1234567: "1,234,567 files"} {
+		if got := format(val); got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+	humanized := NewSuffixFormatter("rows", true)
+	if got := humanized(1204); got != "1.2k rows" {
+		t.Fatal("want", "1.2k rows", "got", got)
+	}
+	for val := 0; val < 1<<30; val = val*7 + 1 {
+		if got := humanized(val); len(got) > len("999.9k rows") {
+			t.Fatal("want", "at most 11 characters", "got", got)
+		}
+	}
+}
+
+func TestBarConsume(t *testing.T) {
+	b := NewBar(UnknownTotal)
+	ch := make(chan int)
+	done := b.Consume(ch)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch <- 1
+			ch <- 2
+		}()
+	}
+	wg.Wait()
+	if b.IsCompleted() {
+		t.Fatal("want", "bar in progress", "got", "completed")
+	}
+	close(ch)
+	<-done
+	if b.Current() != 30 || b.Total != 30 {
+		t.Fatal("want", 30, "got", b.Current(), b.Total)
+	}
+}
+
+func TestBarListenChannel(t *testing.T) {
+	b := NewBar(10000)
+	fanIn := make(chan int)
+	done := b.ListenChannel(fanIn, false)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		producer := make(chan int, 16)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for n := range producer {
+				fanIn <- n
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			defer close(producer)
+			for j := 0; j < 2500; j++ {
+				producer <- 1
+			}
+		}()
+	}
+	wg.Wait()
+	close(fanIn)
+	<-done
+	if b.Current() != 10000 || !b.IsCompleted() {
+		t.Fatal("want", 10000, "got", b.Current())
+	}
+
+	// the goroutine exits on failure even though the channel is never closed
+	b = NewBar(UnknownTotal)
+	values := make(chan int)
+	done = b.ListenValues(values, true)
+	values <- 5
+	values <- 3
+	b.Fail(errors.New("failed"))
+	<-done
+	if b.Current() != 3 || b.IsCompleted() {
+		t.Fatal("want", 3, "got", b.Current())
+	}
+
+	b = NewBar(UnknownTotal)
+	values = make(chan int)
+	done = b.ListenValues(values, true)
+	values <- 7
+	close(values)
+	<-done
+	if b.Current() != 7 || !b.IsCompleted() {
+		t.Fatal("want", 7, "got", b.Current(), b.Total)
+	}
+}
+
+func TestGroupedFormatter(t *testing.T) {
+	for val, want := range map[int]string{
+		0:        "0",
+		7:        "7",
+		999:      "999",
+		1000:     "1,000",
+		-1000:    "-1,000",
+		-999:     "-999",
+		1000000:  "1,000,000",
+		12493021: "12,493,021",
+	} {
+		if got := GroupedFormatter(val); got != want {
+			t.Fatal("want", want, "got", got)
+		}
+	}
+	if got := NewGroupedFormatter(' ')(-1234567); got != "-1 234 567" {
+		t.Fatal("want", "-1 234 567", "got", got)
+	}
+	if got := NewGroupedFormatter('.')(1234); got != "1.234" {
+		t.Fatal("want", "1.234", "got", got)
+	}
+	if got := NewGroupedFormatter(0xD800)(1234); got != "1\uFFFD234" {
+		t.Fatal("want", "1\uFFFD234", "got", got)
+	}
+}
+
+func BenchmarkGroupedFormatter(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GroupedFormatter(12493021 + i)
+	}
+}
+
+func TestNewRateFormatter(t *testing.T) {
+	for _, tc := range []struct {
+		base UnitFormatter
+		rate float64
+		want string
+	}{
+		{BytesFormatter, 3.2 * 1024 * 1024, "3.20MiB/s"},
+		{BytesFormatter, 0.4, "0.4B/s"},
+		{BytesFormatter, 0, "0B/s"},
+		{HumanCountFormatter, 1234, "1.2k/s"},
+		{DefaultFormatter, 0.4, "0.4/s"},
+		{DefaultFormatter, -3, "0/s"},
+		{NewSuffixFormatter("files", false), 0.5, "0.5 files/s"},
+	} {
+		if got := NewRateFormatter(tc.base)(tc.rate); got != tc.want {
+			t.Fatal("want", tc.want, "got", got)
+		}
+	}
+}
+
+func TestBarReport(t *testing.T) {
+	b := NewBar(10).AppendCompleted()
+	b.Width = 12
+	b.Set(5)
+	if got, want := b.Report(), "[=====-----] 50% (5/10)"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	b.SetTotal(UnknownTotal)
+	if got, want := b.Report(), "[----------] (5)"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarReportEmptyTotal(t *testing.T) {
+	b := NewBar(0)
+	b.Width = 5
+	if got, want := b.Report(), "[===] 100% (0/0)"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarTrail(t *testing.T) {
+	b := NewBar(10)
+	b.Width = 10
+	b.Trail = 2
+	b.Set(7)
+	if got, want := b.String(), "[===~~>--]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	b.Trail = 0
+	if got, want := b.String(), "[=====>--]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	b.Trail = 20
+	b.Set(10)
+	if got, want := b.String(), "[========]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBytesPairFormatter(t *testing.T) {
+	for _, tc := range []struct {
+		current, total int
+		want           [2]string
+	}{
+		{900 * 1024, 1288490189, [2]string{"0.00GiB", "1.20GiB"}},
+		{500, 1023, [2]string{"500B", "1023B"}},
+		{1023, 1024, [2]string{"1.00KiB", "1.00KiB"}},
+		{1536, 2 * 1024 * 1024, [2]string{"0.00MiB", "2.00MiB"}},
+		{1536, UnknownTotal, [2]string{"1.50KiB", "0"}},
+	} {
+		current, total := BytesPairFormatter(tc.current, tc.total)
+		if current != tc.want[0] || total != tc.want[1] {
+			t.Fatal("want", tc.want, "got", current, total)
+		}
+	}
+	b := NewBar(2 * 1024 * 1024)
+	b.PairFormatter = NewBytesPairFormatter(BytesPrecision(1))
+	b.Set(900 * 1024)
+	if got := b.FormattedCurrent() + "/" + b.FormattedTotal(); got != "0.9MiB/2.0MiB" {
+		t.Fatal("want", "0.9MiB/2.0MiB", "got", got)
+	}
+}
+
+func TestBarClone(t *testing.T) {
+	b := NewBar(10).PrependFunc(func(b *Bar) string { return "a" })
+	b.Width = 5
+	b.FillRune = '#'
+	b.Set(5)
+	c := b.Clone().AppendFunc(func(b *Bar) string { return "b" })
+	if c.Current() != 0 || c.Total != 10 {
+		t.Fatal("want", 0, 10, "got", c.Current(), c.Total)
+	}
+	c.Set(10)
+	if got, want := c.String(), "a [###] b"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	if got, want := b.String(), "a [>--]"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarRemaining(t *testing.T) {
+	b := NewBar(2048)
+	b.UnitFormatter = BytesFormatter
+	b.Set(512)
+	if b.Remaining() != 1536 || b.FormattedRemaining() != "1.50KiB" {
+		t.Fatal("want", 1536, "got", b.Remaining(), b.FormattedRemaining())
+	}
+	b.SetTotal(256)
+	if b.Remaining() != 0 {
+		t.Fatal("want", 0, "got", b.Remaining())
+	}
+	b.SetTotal(UnknownTotal)
+	if b.Remaining() != 0 {
+		t.Fatal("want", 0, "got", b.Remaining())
+	}
+}
+
+func TestBarMultibyteFormatterAlignment(t *testing.T) {
+	format := func(val int) string {
+		if val < 1000 {
+			return strconv.Itoa(val) + "\u00a0B"
+		}
+		return strconv.Itoa(val/1000) + "\u00a0kB"
+	}
+	var widths []int
+	for _, val := range []int{5, 999, 1000} {
+		b := NewBar(1000).AppendFunc(func(b *Bar) string {
+			return strutil.PadLeft(b.FormattedCurrent(), 8, ' ')
+		}).AppendCompleted()
+		b.Width = 10
+		b.UnitFormatter = format
+		b.Set(val)
+		widths = append(widths, b.RenderedWidth())
+	}
+	if widths[0] != widths[1] || widths[1] != widths[2] {
+		t.Fatal("want", "equal widths", "got", widths)
+	}
+}
+
+// germanPrinter formats numbers with the decimal comma and the grouping dot used in German
+type germanPrinter struct{}
+
+func (germanPrinter) Sprintf(format string, a ...interface{}) string {
+	return strings.NewReplacer(".", ",", ",", ".", "%", " %").Replace(fmt.Sprintf(format, a...))
+}
+
+func TestBarNumberPrinter(t *testing.T) {
+	b := NewBar(4096).AppendCompleted().PrependFunc(func(b *Bar) string { return b.FormattedCurrent() })
+	b.Width = 3
+	b.NumberPrinter = germanPrinter{}
+	b.UnitFormatter = NewBytesFormatter(BytesNumberPrinter(germanPrinter{}), BytesSpace())
+	b.Set(1536)
+	if got, want := b.String(), "1,50 KiB [-]  38 %"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+
+	DefaultNumberPrinter = germanPrinter{}
+	defer func() { DefaultNumberPrinter = nil }()
+	if got, want := HumanCountFormatter(1234567), "1,2M"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+}
+
+func TestBarBytesWidth(t *testing.T) {
+	b := NewBar(10).AppendFunc(func(b *Bar) string { return "\x1b[31mfailed\x1b[0m" })
+	b.Width = 5
+	for _, tc := range []struct {
+		max  int
+		want string
+	}{
+		{20, "[---] \x1b[31mfailed\x1b[0m"},
+		{12, "[---] \x1b[31mfailed\x1b[0m"},
+		{9, "[---] \x1b[31mfa…\x1b[0m"},
+		{3, "[-…\x1b[31m\x1b[0m"},
+		{0, ""},
+	} {
+		got := b.BytesWidth(tc.max)
+		if string(got) != tc.want {
+			t.Fatalf("want %q, got %q", tc.want, got)
+		}
+		if w := displayWidth(got); w > tc.max {
+			t.Fatal("want", tc.max, "got", w)
+		}
+	}
+}
+
+func TestBarPlainString(t *testing.T) {
+	defer func(f func() bool) { colorTerminal = f }(colorTerminal)
+	colorTerminal = func() bool { return true }
+	colored := NewBar(10).AppendColoredPercent()
+	plain := NewBar(10).AppendCompleted()
+	styled := NewBar(10).AppendFunc(func(b *Bar) string { return "\x1b[1m" + b.CompletedPercentString() + "\x1b[0m" })
+	for _, b := range []*Bar{colored, plain, styled} {
+		b.Width = 10
+		b.Set(4)
+	}
+	if colored.String() == plain.String() {
+		t.Fatal("want", "colors in String", "got", colored.String())
+	}
+	if got, want := colored.PlainString(), plain.String(); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	if got, want := plain.PlainString(), plain.String(); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	// the escape sequences of custom decorators are left to strutil.StripANSI
+	if got, want := styled.PlainString(), styled.String(); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestBarDecoratorPanic(t *testing.T) {
+	var recovered []interface{}
+	DecoratorPanicHandler = func(b *Bar, r interface{}) { recovered = append(recovered, r) }
+	defer func() { DecoratorPanicHandler = nil }()
+
+	var m map[string]int
+	b := NewBar(10).AppendFunc(func(b *Bar) string {
+		m["count"]++
+		return "unreachable"
+	}).PrependFunc(func(b *Bar) string { panic("boom") }).AppendCompleted()
+	b.Width = 5
+	if got, want := b.String(), "!ERR [---] !ERR   0%"; got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	if len(recovered) != 2 || recovered[0] != "boom" {
+		t.Fatal("want", 2, "got", recovered)
+	}
+}
+
+func benchmarkBytes(b *testing.B, decorators int) {
+	bar := NewBar(1000)
+	for i := 0; i < decorators; i++ {
+		if i%2 == 0 {
+			bar.AppendFunc(func(b *Bar) string { return "append" })
+		} else {
+			bar.PrependFunc(func(b *Bar) string { return "prepend" })
+		}
+	}
+	bar.Set(500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bar.Bytes()
+	}
+}
+
+func BenchmarkBytes(b *testing.B) {
+	benchmarkBytes(b, 0)
+}
+
+func BenchmarkBytes2Decorators(b *testing.B) {
+	benchmarkBytes(b, 2)
+}
+
+func BenchmarkBytes8Decorators(b *testing.B) {
+	benchmarkBytes(b, 8)
+}
+
+func TestBarElapsedSinceStart(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(10).SetClock(clock).AppendElapsed()
+	b.Width = 3
+	clock.Advance(time.Minute)
+	if got := b.ElapsedSinceStart(); got != 0 {
+		t.Fatal("want", 0, "got", got)
+	}
+	b.Incr()
+	clock.Advance(5 * time.Second)
+	// stalled, the elapsed time keeps ticking
+	if got, want := b.String(), "[-]    5s"; got != want || b.TimeElapsed() != 0 {
+		t.Fatal("want", want, "got", got, b.TimeElapsed())
+	}
+	b.Finish()
+	clock.Advance(time.Hour)
+	if got := b.ElapsedSinceStart(); got != 5*time.Second {
+		t.Fatal("want", 5*time.Second, "got", got)
+	}
+}
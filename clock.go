@@ -0,0 +1,35 @@
+package uiprogress
+
+import "time"
+
+// Clock tells the time to bars and containers, allowing time to be controlled in tests. See the testutil
+// package for a fake clock.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After returns a channel that receives the current time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// DefaultClock is the clock of new bars and containers, which reads the system time
+var DefaultClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// since returns the time elapsed from t to now, or 0 when the clock was set back before t. The times of the
+// system clock carry a monotonic reading, which Sub uses, so this only happens with other clocks.
+func since(now, t time.Time) time.Duration {
+	if d := now.Sub(t); d > 0 {
+		return d
+	}
+	return 0
+}
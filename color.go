@@ -0,0 +1,82 @@
+package uiprogress
+
+// ColorStop is the color of the percent rendered by AppendColoredPercent from a percent on, see
+// Bar.PercentColorStops
+type ColorStop struct {
+	// From is the percent the color starts at
+	From float64
+
+	// Color is the escape sequence selecting the color, such as "\x1b[31m" for red
+	Color string
+}
+
+// DefaultPercentColorStops color the percent red under 33%, yellow under 66% and green from there on
+var DefaultPercentColorStops = []ColorStop{
+	{From: 0, Color: "\x1b[31m"},
+	{From: 33, Color: "\x1b[33m"},
+	{From: 66, Color: "\x1b[32m"},
+}
+
+// colorReset resets the color selected by a ColorStop
+const colorReset = "\x1b[0m"
+
+// colorTerminal returns true when stdout is a terminal, which the colors of bars outside of a container are
+// written to
+var colorTerminal = func() bool {
+	_, ok := terminalWidth()
+	return ok
+}
+
+// AppendColoredPercent appends the completion percent like AppendCompleted, colored by the stop of
+// PercentColorStops it falls in. The colors take no columns and are left out from PlainString and when
+// the output is not a terminal, which is the Out of the container of the bar, or stdout for a bar outside
+// of a container.
+func (b *Bar) AppendColoredPercent() *Bar {
+	b.appendDecorator(func(b *Bar) (string, string) {
+		s := b.CompletedPercentString()
+		if b.TotalUnknown() || !b.colored() {
+			return s, s
+		}
+		if color := b.percentColor(b.CompletedPercent()); color != "" {
+			return color + s + colorReset, s
+		}
+		return s, s
+	})
+	return b
+}
+
+// setTerminal sets whether the output of the container of the bar is a terminal, see colored. A state of 0
+// is for a bar outside of a container.
+func (b *Bar) setTerminal(state int8) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.terminal = state
+}
+
+// colored returns true when the output of the bar is a terminal, which colors are written to
+func (b *Bar) colored() bool {
+	b.mtx.RLock()
+	state := b.terminal
+	b.mtx.RUnlock()
+	if state == 0 {
+		return colorTerminal()
+	}
+	return state > 0
+}
+
+// percentColor returns the color of the last stop starting at or below pct, or "" when there is none
+func (b *Bar) percentColor(pct float64) string {
+	b.mtx.RLock()
+	stops := b.PercentColorStops
+	b.mtx.RUnlock()
+	if stops == nil {
+		stops = DefaultPercentColorStops
+	}
+	color := ""
+	for _, s := range stops {
+		if s.From <= pct {
+			color = s.Color
+		}
+	}
+	return color
+}
@@ -0,0 +1,79 @@
+package uiprogress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBarAppendColoredPercent(t *testing.T) {
+	defer func(f func() bool) { colorTerminal = f }(colorTerminal)
+	colorTerminal = func() bool { return true }
+
+	b := NewBar(100).AppendColoredPercent()
+	b.Width = 4
+	for _, c := range []struct {
+		current int
+		want    string
+	}{
+		{10, "[--] \x1b[31m 10%\x1b[0m"},
+		{50, "[>-] \x1b[33m 50%\x1b[0m"},
+		{66, "[>-] \x1b[32m 66%\x1b[0m"},
+	} {
+		b.Set(c.current)
+		if got := b.String(); got != c.want {
+			t.Fatalf("want %q, got %q", c.want, got)
+		}
+		if got := b.RenderedWidth(); got != 9 {
+			t.Fatal("want", 9, "got", got)
+		}
+	}
+	if got, want := b.PlainString(), "[>-]  66%"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+
+	b.PercentColorStops = []ColorStop{{From: 90, Color: "\x1b[1m"}}
+	if got, want := b.String(), "[>-]  66%"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+
+	// no colors when stdout is not a terminal
+	colorTerminal = func() bool { return false }
+	b.PercentColorStops = nil
+	if got, want := b.String(), "[>-]  66%"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestBarAppendColoredPercentContainer(t *testing.T) {
+	defer func(f func() bool) { colorTerminal = f }(colorTerminal)
+	colorTerminal = func() bool { return true }
+
+	// the output of the container decides, whatever stdout is
+	f, err := ioutil.TempFile("", "uiprogress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	p := New()
+	p.SetOut(f)
+	b := p.AddBar(100).AppendColoredPercent()
+	b.Set(50)
+	if got := b.String(); strings.Contains(got, "\x1b[") {
+		t.Fatalf("want no colors, got %q", got)
+	}
+
+	colorTerminal = func() bool { return false }
+	p.SetOut(&bytes.Buffer{})
+	p.print(true)
+	if got := b.String(); !strings.Contains(got, "\x1b[33m") {
+		t.Fatalf("want colors, got %q", got)
+	}
+	p.RemoveBar(b)
+	if got := b.String(); strings.Contains(got, "\x1b[") {
+		t.Fatalf("want no colors, got %q", got)
+	}
+}
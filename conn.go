@@ -0,0 +1,35 @@
+package uiprogress
+
+import (
+	"io"
+	"net"
+)
+
+// WrapConn wraps c so that reads advance readBar and writes advance writeBar, either of which may be nil.
+// The rest of net.Conn, including deadlines and Close, is passed through to c. Bars with an unknown total
+// count the bytes transferred until the connection is done.
+func WrapConn(c net.Conn, readBar, writeBar *Bar, opts ...ReaderOption) net.Conn {
+	pc := &progressConn{Conn: c, r: c, w: c}
+	if readBar != nil {
+		pc.r = readBar.ReadUpdater(c, opts...)
+	}
+	if writeBar != nil {
+		pc.w = writeBar.WriteUpdater(c, opts...)
+	}
+	return pc
+}
+
+type progressConn struct {
+	net.Conn
+
+	r io.Reader
+	w io.Writer
+}
+
+func (c *progressConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func (c *progressConn) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
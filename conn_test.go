@@ -0,0 +1,52 @@
+package uiprogress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func TestWrapConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const sent, received = 300000, 200000
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.CopyN(ioutil.Discard, c, sent)
+		c.Write(bytes.Repeat([]byte("x"), received))
+	}()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	readBar, writeBar := NewBar(UnknownTotal), NewBar(sent)
+	conn := WrapConn(c, readBar, writeBar)
+	defer conn.Close()
+	if conn.RemoteAddr().String() != ln.Addr().String() {
+		t.Fatal("want", ln.Addr(), "got", conn.RemoteAddr())
+	}
+
+	if _, err := conn.Write(bytes.Repeat([]byte("x"), sent)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, conn); err != nil {
+		t.Fatal(err)
+	}
+	if writeBar.Current() != sent {
+		t.Fatal("want", sent, "got", writeBar.Current())
+	}
+	if readBar.Current() != received || readBar.Total != received {
+		t.Fatal("want", received, "got", readBar.Current(), readBar.Total)
+	}
+}
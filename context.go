@@ -0,0 +1,95 @@
+package uiprogress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReadUpdaterContext is like ReadUpdater but fails reads once ctx is done, returning an error wrapping
+// ctx.Err() and failing the bar with it. The context is checked before every read. When input supports
+// read deadlines, like net.Conn and *os.File, a blocked read is interrupted as soon as ctx is done. A bar
+// whose reader already reached EOF is not failed.
+func (b *Bar) ReadUpdaterContext(ctx context.Context, input io.Reader, opts ...ReaderOption) io.Reader {
+	return b.ReadUpdater(&contextReader{ctx: ctx, r: input, bar: b}, opts...)
+}
+
+// readDeadliner is implemented by readers whose blocking reads can be interrupted
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// contextReader fails reads, and the bar, once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+	bar *Bar
+	eof bool
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if r.eof {
+		return 0, io.EOF
+	}
+	if err := r.ctx.Err(); err != nil {
+		return 0, r.fail(err)
+	}
+	if d, ok := r.r.(readDeadliner); ok && r.ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-r.ctx.Done():
+				d.SetReadDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+	}
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		r.eof = true
+		return n, err
+	}
+	if cerr := r.ctx.Err(); cerr != nil {
+		return n, r.fail(cerr)
+	}
+	return n, err
+}
+
+func (r *contextReader) fail(err error) error {
+	err = fmt.Errorf("read canceled: %w", err)
+	r.bar.Fail(err)
+	return err
+}
+
+// NewDeadlineBar returns a bar that fills itself as the deadline of ctx approaches, like NewTimedBar with a
+// duration running from the first time the bar is rendered to the deadline. The bar never completes by
+// itself, only with Finish, and fails with ctx.Err() when ctx is done first. A context without a deadline
+// gives a bar with an unknown total, which still fails when ctx is done.
+func NewDeadlineBar(ctx context.Context) *Bar {
+	var b *Bar
+	if deadline, ok := ctx.Deadline(); ok {
+		d := time.Until(deadline)
+		if d < time.Millisecond {
+			// the context is done, or about to be, and fails the bar
+			d = time.Millisecond
+		}
+		b = NewTimedBar(d)
+		b.deadline = deadline
+	} else {
+		b = NewBar(UnknownTotal)
+	}
+	if done := ctx.Done(); done != nil {
+		go func() {
+			select {
+			case <-done:
+				if !b.IsCompleted() {
+					b.Fail(ctx.Err())
+				}
+			case <-b.failed():
+			}
+		}()
+	}
+	return b
+}
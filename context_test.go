@@ -0,0 +1,97 @@
+package uiprogress
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestReadUpdaterContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := NewBar(100)
+	r := b.ReadUpdaterContext(ctx, strings.NewReader(strings.Repeat("x", 100)))
+	buf := make([]byte, 40)
+	r.Read(buf)
+	cancel()
+	if _, err := r.Read(buf); !errors.Is(err, context.Canceled) {
+		t.Fatal("want", context.Canceled, "got", err)
+	}
+	if b.Current() != 40 || !errors.Is(b.Err(), context.Canceled) {
+		t.Fatal("want", 40, context.Canceled, "got", b.Current(), b.Err())
+	}
+
+	// cancelling after EOF leaves the completed bar alone
+	ctx, cancel = context.WithCancel(context.Background())
+	b = NewBar(100)
+	r = b.ReadUpdaterContext(ctx, strings.NewReader(strings.Repeat("x", 100)))
+	ioutil.ReadAll(r)
+	cancel()
+	if _, err := r.Read(buf); err != io.EOF {
+		t.Fatal("want", io.EOF, "got", err)
+	}
+	if !b.IsCompleted() || b.Err() != nil {
+		t.Fatal("want", "completed bar", "got", b.Current(), b.Err())
+	}
+}
+
+func TestReadUpdaterContextBlocked(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	b := NewBar(100)
+	if _, err := b.ReadUpdaterContext(ctx, pr).Read(make([]byte, 10)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("want", context.DeadlineExceeded, "got", err)
+	}
+}
+
+func TestNewDeadlineBar(t *testing.T) {
+	start := time.Now()
+	clock := testutil.NewFakeClock(start)
+	ctx, cancel := context.WithDeadline(context.Background(), start.Add(time.Hour))
+	b := NewDeadlineBar(ctx).SetClock(clock)
+	b.Width = 12
+	if got, want := b.String(), "[----------]"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	clock.Advance(30 * time.Minute)
+	if got, want := b.String(), "[====>-----]"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+
+	// reaching the deadline on the clock of the bar does not complete it
+	clock.Advance(time.Hour)
+	b.Bytes()
+	if b.IsCompleted() || b.Current() != b.Total-1 {
+		t.Fatal("want", b.Total-1, "got", b.Current())
+	}
+	cancel()
+	<-b.failed()
+	if !errors.Is(b.Err(), context.Canceled) {
+		t.Fatal("want", context.Canceled, "got", b.Err())
+	}
+
+	// a context without a deadline gives an indeterminate bar
+	ctx, cancel = context.WithCancel(context.Background())
+	b = NewDeadlineBar(ctx)
+	if b.Total != UnknownTotal {
+		t.Fatal("want", UnknownTotal, "got", b.Total)
+	}
+	cancel()
+	<-b.failed()
+	if !errors.Is(b.Err(), context.Canceled) {
+		t.Fatal("want", context.Canceled, "got", b.Err())
+	}
+}
@@ -0,0 +1,108 @@
+package uiprogress
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// copyBufferSize is the size of the buffers reused across calls to Copy
+const copyBufferSize = 32 * 1024
+
+var copyBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, copyBufferSize)
+		return &b
+	},
+}
+
+// CopyOption configures Copy and CopyContext
+type CopyOption func(*copyConfig)
+
+type copyConfig struct {
+	bar       *Bar
+	progress  *Progress
+	label     string
+	formatter UnitFormatter
+}
+
+// CopyBar tracks the copy with b instead of creating a new bar
+func CopyBar(b *Bar) CopyOption {
+	return func(c *copyConfig) {
+		c.bar = b
+	}
+}
+
+// CopyProgress adds the bar created for the copy to p
+func CopyProgress(p *Progress) CopyOption {
+	return func(c *copyConfig) {
+		c.progress = p
+	}
+}
+
+// CopyLabel prepends label to the bar created for the copy
+func CopyLabel(label string) CopyOption {
+	return func(c *copyConfig) {
+		c.label = label
+	}
+}
+
+// CopyFormatter sets the UnitFormatter of the bar created for the copy. Defaults to BytesFormatter.
+func CopyFormatter(f UnitFormatter) CopyOption {
+	return func(c *copyConfig) {
+		c.formatter = f
+	}
+}
+
+// Copy copies from src to dst like io.Copy, tracking the bytes written to dst with a bar of the given
+// total. A negative total is unknown. On success the bar is finished, otherwise it is failed with the
+// error and keeps the count of bytes written. Nothing is copied when the bar added to the container of
+// CopyProgress is not valid, see Bar.Validate.
+func Copy(dst io.Writer, src io.Reader, total int64, opts ...CopyOption) (int64, error) {
+	return CopyContext(context.Background(), dst, src, total, opts...)
+}
+
+// CopyContext is like Copy but aborts the copy with the context's error once ctx is done
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader, total int64, opts ...CopyOption) (int64, error) {
+	c := copyConfig{formatter: BytesFormatter}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	bar := c.bar
+	if bar == nil {
+		t := UnknownTotal
+		if total >= 0 {
+			t = int(total)
+		}
+		if c.progress != nil {
+			bar = c.progress.newBar(t)
+		} else {
+			bar = NewBar(t)
+		}
+		bar.UnitFormatter = c.formatter
+		if c.label != "" {
+			label := c.label
+			bar.PrependFunc(func(b *Bar) string {
+				return label
+			})
+		}
+		if c.progress != nil {
+			if _, err := c.progress.AddBarChecked(bar); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if ctx.Done() != nil {
+		src = &contextReader{ctx: ctx, r: src, bar: bar}
+	}
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	n, err := io.CopyBuffer(bar.WriteUpdater(dst), src, *buf)
+	if err != nil {
+		bar.Fail(err)
+		return n, err
+	}
+	bar.Finish()
+	return n, nil
+}
@@ -0,0 +1,87 @@
+package uiprogress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// limitWriter accepts n bytes and then fails
+type limitWriter struct {
+	n int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, io.ErrShortWrite
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestCopy(t *testing.T) {
+	data := strings.Repeat("x", 100000)
+	p := New()
+	var dst bytes.Buffer
+	n, err := Copy(&dst, strings.NewReader(data), int64(len(data)), CopyProgress(p), CopyLabel("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar := p.Bars[0]
+	if n != int64(len(data)) || bar.Current() != len(data) || !bar.IsCompleted() {
+		t.Fatal("want", len(data), "got", n, bar.Current())
+	}
+	if !strings.HasPrefix(bar.String(), "data ") {
+		t.Fatal("want", "data", "got", bar.String())
+	}
+
+	// bars made invalid by the defaults of the container are not added
+	p = New()
+	p.ApplyDefaults(func(b *Bar) { b.Width = -1 })
+	if n, err := Copy(&dst, strings.NewReader(data), int64(len(data)), CopyProgress(p)); !errors.Is(err, ErrInvalidBar) || n != 0 || len(p.Bars) != 0 {
+		t.Fatal("want", ErrInvalidBar, "got", err, n, len(p.Bars))
+	}
+
+	bar = NewBar(len(data))
+	n, err = Copy(&limitWriter{n: 1000}, strings.NewReader(data), int64(len(data)), CopyBar(bar))
+	if err != io.ErrShortWrite || bar.Err() != err {
+		t.Fatal("want", io.ErrShortWrite, "got", err, bar.Err())
+	}
+	if n != 1000 || bar.Current() != 1000 {
+		t.Fatal("want", 1000, "got", n, bar.Current())
+	}
+
+	readErr := errors.New("read failed")
+	bar = NewBar(len(data))
+	src := io.MultiReader(strings.NewReader(data[:500]), &errReader{err: readErr})
+	if _, err = Copy(ioutil.Discard, src, int64(len(data)), CopyBar(bar)); err != readErr || bar.Err() != readErr {
+		t.Fatal("want", readErr, "got", err, bar.Err())
+	}
+	if bar.Current() != 500 {
+		t.Fatal("want", 500, "got", bar.Current())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bar = NewBar(len(data))
+	if _, err = CopyContext(ctx, ioutil.Discard, strings.NewReader(data), int64(len(data)), CopyBar(bar)); !errors.Is(err, context.Canceled) {
+		t.Fatal("want", context.Canceled, "got", err)
+	}
+	if !errors.Is(bar.Err(), context.Canceled) {
+		t.Fatal("want", context.Canceled, "got", bar.Err())
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,20 @@
+package uiprogress
+
+// SetCountdown sets whether the bar counts down, starting full and draining as progress is made, such as for
+// a TTL or a "retry in 12s" display. The fill and CompletedPercentString show what remains, while Set, Incr
+// and the completion of the bar work as usual. With AutoAdvance, the bar drains over time, and
+// AppendTimeRemaining shows the time left.
+func (b *Bar) SetCountdown(enabled bool) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.countdown = enabled
+	b.dirty = true
+	return b
+}
+
+// isCountdown returns true when the bar counts down, see SetCountdown
+func (b *Bar) isCountdown() bool {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.countdown
+}
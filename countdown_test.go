@@ -0,0 +1,31 @@
+package uiprogress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestCountdownBar(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewTimedBar(10 * time.Second).SetClock(clock).SetCountdown(true).AppendCompleted().AppendTimeRemaining()
+	b.Width = 12
+	for _, want := range []string{
+		"[==========] 100%   10s",
+		"[====>-----]  50%    5s",
+		"[----------]   0%   ---",
+	} {
+		if got := b.String(); got != want {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+		clock.Advance(5 * time.Second)
+	}
+
+	b = NewBar(4).SetCountdown(true)
+	b.Width = 10
+	b.Incr()
+	if got, want := b.String(), "[======>-]"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
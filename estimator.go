@@ -0,0 +1,105 @@
+package uiprogress
+
+import "time"
+
+// Estimator estimates the time remaining of a bar from samples of its progress, see Bar.SetEstimator. The
+// methods are called with the bar locked, so they must not call the methods of the bar.
+type Estimator interface {
+	// Sample records that the bar reached current at t
+	Sample(t time.Time, current int)
+
+	// Remaining returns the estimated time for the bar to go from current to total, or 0 when there is not
+	// enough progress to estimate from
+	Remaining(current, total int) time.Duration
+}
+
+// LinearEstimator returns an estimator extrapolating from the average rate since the first sample, which
+// is the estimate of bars without an estimator
+func LinearEstimator() Estimator {
+	return &linearEstimator{}
+}
+
+type linearEstimator struct {
+	start, last time.Time
+	current     int
+}
+
+func (e *linearEstimator) Sample(t time.Time, current int) {
+	if e.start.IsZero() {
+		e.start = t
+	}
+	e.last, e.current = t, current
+}
+
+func (e *linearEstimator) Remaining(current, total int) time.Duration {
+	return remaining(total-current, float64(e.current)/since(e.last, e.start).Seconds())
+}
+
+// EWMAEstimator returns an estimator extrapolating from an exponentially weighted moving average of the
+// rate between samples, which follows changes in speed, such as after a slow start, rather than the
+// average since the start. Each new rate is weighted by alpha, between 0 and 1, and the average by 1-alpha.
+func EWMAEstimator(alpha float64) Estimator {
+	return &ewmaEstimator{alpha: alpha}
+}
+
+type ewmaEstimator struct {
+	alpha   float64
+	rate    float64
+	last    time.Time
+	current int
+	sampled bool
+}
+
+func (e *ewmaEstimator) Sample(t time.Time, current int) {
+	if e.last.IsZero() {
+		e.last, e.current = t, current
+		return
+	}
+	d := since(t, e.last)
+	if d <= 0 {
+		// the progress is added to the next sample taken later
+		return
+	}
+	rate := float64(current-e.current) / d.Seconds()
+	if e.sampled {
+		rate = e.alpha*rate + (1-e.alpha)*e.rate
+	}
+	e.rate, e.sampled = rate, true
+	e.last, e.current = t, current
+}
+
+func (e *ewmaEstimator) Remaining(current, total int) time.Duration {
+	return remaining(total-current, e.rate)
+}
+
+// remaining returns the time to make n progress at rate per second, or 0 when the rate is unknown
+func remaining(n int, rate float64) time.Duration {
+	if n <= 0 || !(rate > 0) {
+		return 0
+	}
+	return time.Duration(float64(n) / rate * float64(time.Second))
+}
+
+// etaRate returns the rate matching the estimate of the estimator of the bar, or the average rate without
+// an estimator or an estimate
+func (b *Bar) etaRate() float64 {
+	b.mtx.RLock()
+	e, current, total := b.estimator, b.current, b.Total
+	b.mtx.RUnlock()
+	if e != nil && total > current {
+		if d := b.TimeRemaining(); d > 0 {
+			return float64(total-current) / d.Seconds()
+		}
+	}
+	return b.Rate()
+}
+
+// SetEstimator sets the estimator of the time remaining, used by TimeRemaining and AppendTimeRemaining and
+// by the overall ETA of the container. An estimator keeps the samples of a single bar and must not be shared.
+// A nil estimator restores the default, which is like LinearEstimator.
+func (b *Bar) SetEstimator(e Estimator) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.estimator = e
+	return b
+}
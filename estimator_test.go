@@ -0,0 +1,46 @@
+package uiprogress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestEstimators(t *testing.T) {
+	remaining := func(e Estimator) time.Duration {
+		clock := testutil.NewFakeClock(time.Unix(0, 0))
+		b := NewBar(1000).SetClock(clock).SetEstimator(e)
+		// a stall at 1 per second, then a burst at 100 per second
+		for i := 0; i < 10; i++ {
+			b.Add(1)
+			clock.Advance(time.Second)
+		}
+		for i := 0; i < 2; i++ {
+			clock.Advance(time.Second)
+			b.Add(100)
+		}
+		return b.TimeRemaining()
+	}
+
+	// 210 done over 12 seconds, 790 left
+	linear := remaining(LinearEstimator())
+	done, left := 210.0, 790.0
+	if want := time.Duration(left / (done / 12) * float64(time.Second)); linear != want {
+		t.Fatal("want", want, "got", linear)
+	}
+	if def := remaining(nil); def != linear {
+		t.Fatal("want", linear, "got", def)
+	}
+	// the rates between samples are 1 until the burst, then 50 and 100, averaged to 62.75
+	ewma := remaining(EWMAEstimator(0.5))
+	if want := time.Duration(left / 62.75 * float64(time.Second)); ewma != want {
+		t.Fatal("want", want, "got", ewma)
+	}
+
+	progress := New()
+	progress.AddConfiguredBar(NewBar(10).SetEstimator(EWMAEstimator(0.5)))
+	if eta := progress.OverallETA(); eta != 0 {
+		t.Fatal("want", 0, "got", eta)
+	}
+}
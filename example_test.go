@@ -101,3 +101,16 @@ func ExampleBar_Incr() {
 	wg.Wait()
 	uiprogress.Stop()
 }
+
+func ExampleNewSuffixFormatter() {
+	bar := uiprogress.NewBar(12500)
+	bar.UnitFormatter = uiprogress.NewSuffixFormatter("rows", false)
+	bar.Set(1204)
+	fmt.Println(bar.FormattedCurrent(), "of", bar.FormattedTotal())
+
+	bar.UnitFormatter = uiprogress.NewSuffixFormatter("rows", true)
+	fmt.Println(bar.FormattedCurrent(), "of", bar.FormattedTotal())
+	// Output:
+	// 1,204 rows of 12,500 rows
+	// 1.2k rows of 12.5k rows
+}
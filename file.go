@@ -0,0 +1,29 @@
+package uiprogress
+
+import (
+	"io"
+	"io/fs"
+)
+
+// NewBarForFile returns a new bar sized to the file and formatted in bytes, along with a reader that
+// advances the bar as the file is read. *os.File and the files opened from an fs.FS are accepted. Files
+// that have no meaningful size, such as pipes and devices, get a bar with an unknown total.
+func NewBarForFile(f fs.File) (*Bar, io.Reader, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	bar := NewBarForFileInfo(fi)
+	return bar, bar.ReadUpdater(f), nil
+}
+
+// NewBarForFileInfo returns a new bar sized to the file described by fi and formatted in bytes
+func NewBarForFileInfo(fi fs.FileInfo) *Bar {
+	total := UnknownTotal
+	if fi.Mode().IsRegular() {
+		total = int(fi.Size())
+	}
+	bar := NewBar(total)
+	bar.UnitFormatter = BytesFormatter
+	return bar
+}
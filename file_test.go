@@ -0,0 +1,41 @@
+package uiprogress
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNewBarForFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "uiprogress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.Write(make([]byte, 2048))
+	f.Seek(0, io.SeekStart)
+
+	bar, r, err := New().AddBarForFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bar.Total != 2048 || bar.FormattedTotal() != "2.00KiB" {
+		t.Fatal("want", 2048, "got", bar.Total, bar.FormattedTotal())
+	}
+	io.Copy(ioutil.Discard, r)
+	if bar.Current() != 2048 {
+		t.Fatal("want", 2048, "got", bar.Current())
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	pw.Close()
+	if bar, _, _ = NewBarForFile(pr); !bar.TotalUnknown() {
+		t.Fatal("want", "unknown total", "got", bar.Total)
+	}
+}
@@ -0,0 +1,35 @@
+package uiprogress
+
+import (
+	"hash"
+	"io"
+)
+
+// HashUpdater is like ReadUpdater and also feeds every byte read from input to h, so a checksum can be
+// verified while the data is read. The digest is available from Sum once EOF is reached.
+func (b *Bar) HashUpdater(input io.Reader, h hash.Hash, opts ...ReaderOption) *HashProgressor {
+	return &HashProgressor{
+		ReadProgressor: &ReadProgressor{
+			readerConfig: newReaderConfig(opts),
+			bar:          b,
+			input:        io.TeeReader(input, h),
+		},
+		hash: h,
+	}
+}
+
+// HashProgressor is an io.Reader that advances a bar and updates a hash as data is read
+type HashProgressor struct {
+	*ReadProgressor
+
+	hash hash.Hash
+}
+
+// Sum appends the digest of the data read to b and returns the result. It returns nil until the wrapped
+// reader has returned io.EOF, since the digest of partial data is of no use for verification.
+func (p *HashProgressor) Sum(b []byte) []byte {
+	if !p.eof {
+		return nil
+	}
+	return p.hash.Sum(b)
+}
@@ -0,0 +1,31 @@
+package uiprogress
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestHashUpdater(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	bar := NewBar(UnknownTotal)
+	r := bar.HashUpdater(bytes.NewReader(data), sha256.New())
+	if _, err := io.CopyN(ioutil.Discard, r, 10); err != nil {
+		t.Fatal(err)
+	}
+	if sum := r.Sum(nil); sum != nil {
+		t.Fatal("want", nil, "got", sum)
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256(data)
+	if got := r.Sum(nil); !bytes.Equal(got, want[:]) {
+		t.Fatal("want", want, "got", got)
+	}
+	if bar.Current() != len(data) || bar.Total != len(data) {
+		t.Fatal("want", len(data), "got", bar.Current(), bar.Total)
+	}
+}
@@ -0,0 +1,60 @@
+package uiprogress
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+)
+
+// LineUpdater wraps input so that the bar advances by the number of newline-delimited records read rather
+// than bytes, with the total being the number of records expected. A final record without a trailing
+// newline is counted at EOF. With Throttle, n is a number of lines.
+func (b *Bar) LineUpdater(input io.Reader, opts ...ReaderOption) *LineProgressor {
+	return &LineProgressor{
+		readerConfig: newReaderConfig(opts),
+		bar:          b,
+		input:        input,
+	}
+}
+
+// LineProgressor is an io.Reader that advances a bar by the number of lines read
+type LineProgressor struct {
+	readerConfig
+
+	bar     *Bar
+	input   io.Reader
+	bytes   int64
+	partial bool
+	pending pending
+}
+
+func (p *LineProgressor) Read(into []byte) (int, error) {
+	amt, err := p.input.Read(into)
+	if amt > 0 {
+		atomic.AddInt64(&p.bytes, int64(amt))
+		p.partial = into[amt-1] != '\n'
+	}
+	p.chunk(into[:amt])
+	lines := bytes.Count(into[:amt], []byte{'\n'})
+	if err == io.EOF && p.partial {
+		lines++
+		p.partial = false
+	}
+	if err != nil {
+		p.pending.n += lines
+		if cerr := p.pending.flush(p.bar, p.clamp); cerr != nil {
+			return amt, cerr
+		}
+	} else if cerr := p.pending.add(p.bar, &p.readerConfig, lines); cerr != nil {
+		return amt, cerr
+	}
+	if err == io.EOF {
+		p.bar.resolveTotal()
+	}
+	return amt, err
+}
+
+// BytesRead returns the number of bytes read so far. It is safe to call from a decorator.
+func (p *LineProgressor) BytesRead() int64 {
+	return atomic.LoadInt64(&p.bytes)
+}
@@ -0,0 +1,62 @@
+package uiprogress
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestLineUpdater(t *testing.T) {
+	for _, data := range []string{"a,b\nc,d\ne,f\n", "a,b\nc,d\ne,f"} {
+		b := NewBar(3)
+		// read a byte at a time so lines are split across reads
+		r := b.LineUpdater(iotest.OneByteReader(strings.NewReader(data)))
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			t.Fatal(err)
+		}
+		if b.Current() != 3 {
+			t.Fatal("want", 3, "got", b.Current())
+		}
+		if r.BytesRead() != int64(len(data)) {
+			t.Fatal("want", len(data), "got", r.BytesRead())
+		}
+	}
+}
+
+func TestLineUpdaterOptions(t *testing.T) {
+	b := NewBar(3)
+	var chunked []byte
+	r := b.LineUpdater(strings.NewReader("a,b\nc,d\ne,f\n"), Throttle(2, time.Hour),
+		OnChunk(func(p []byte, n int) { chunked = append(chunked, p[:n]...) }))
+	if _, err := r.Read(make([]byte, 4)); err != nil || b.Current() != 0 {
+		t.Fatal("want", 0, "got", b.Current(), err)
+	}
+	if _, err := r.Read(make([]byte, 4)); err != nil || b.Current() != 2 {
+		t.Fatal("want", 2, "got", b.Current(), err)
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if b.Current() != 3 || string(chunked) != "a,b\nc,d\ne,f\n" {
+		t.Fatal("want", 3, "a,b\nc,d\ne,f\n", "got", b.Current(), string(chunked))
+	}
+}
+
+func TestLineUpdaterOverflowAtEOF(t *testing.T) {
+	// the final record arrives together with io.EOF, beyond the total
+	b := NewBar(2)
+	b.Overflow = OverflowFail
+	if _, err := ioutil.ReadAll(b.LineUpdater(&eofReader{data: []byte("a\nb\nc")})); !errors.Is(err, ErrMaxCurrentReached) {
+		t.Fatal("want", ErrMaxCurrentReached, "got", err)
+	}
+
+	b = NewBar(2)
+	b.Overflow = OverflowFail
+	if _, err := ioutil.ReadAll(b.LineUpdater(&eofReader{data: []byte("a\nb\nc")}, Clamp())); err != nil || b.Current() != 2 {
+		t.Fatal("want", 2, "got", b.Current(), err)
+	}
+}
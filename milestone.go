@@ -0,0 +1,63 @@
+package uiprogress
+
+// milestone is a callback run once the bar reaches percent, see OnMilestone
+type milestone struct {
+	percent float64
+	f       func(*Bar)
+	fired   bool
+}
+
+// OnMilestone calls f once the bar reaches percent of its total, such as to start preparing the next stage at
+// 90%. Every milestone is called exactly once, in the order of the percents, including when a single update
+// jumps across several of them, and none is called once the bar failed. The callbacks run on the goroutine
+// updating the bar, or rendering it for the milestones reached by AutoAdvance, outside of the locks of the
+// bar and of its container, so they may call their methods.
+func (b *Bar) OnMilestone(percent float64, f func(*Bar)) *Bar {
+	b.mtx.Lock()
+	defer b.unlock()
+	i := len(b.milestones)
+	for i > 0 && b.milestones[i-1].percent > percent {
+		i--
+	}
+	b.milestones = append(b.milestones, milestone{})
+	copy(b.milestones[i+1:], b.milestones[i:])
+	b.milestones[i] = milestone{percent: percent, f: f}
+	b.reachMilestones()
+	return b
+}
+
+// reachMilestones queues the callbacks of the milestones reached by the bar, to be run by unlock. Callers
+// must hold the lock.
+func (b *Bar) reachMilestones() {
+	if b.err != nil || b.Total < 0 {
+		return
+	}
+	p := percent(b.current, b.Total)
+	for i := range b.milestones {
+		m := &b.milestones[i]
+		if m.percent > p {
+			return
+		}
+		if !m.fired {
+			m.fired = true
+			b.due = append(b.due, m.f)
+		}
+	}
+}
+
+// unlock releases the lock of the bar, then runs the callbacks due, see dispatch
+func (b *Bar) unlock() {
+	due := b.due
+	b.due = nil
+	b.mtx.Unlock()
+	for _, f := range due {
+		f(b)
+	}
+}
+
+// dispatch runs the callbacks of the milestones and stalls reached while rendering, which the container calls
+// once it is unlocked
+func (b *Bar) dispatch() {
+	b.mtx.Lock()
+	b.unlock()
+}
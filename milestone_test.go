@@ -0,0 +1,98 @@
+package uiprogress
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestBarOnMilestone(t *testing.T) {
+	var got []float64
+	b := NewBar(100)
+	for _, p := range []float64{90, 25, 75, 50} {
+		p := p
+		b.OnMilestone(p, func(b *Bar) {
+			// the bar is unlocked
+			if b.Current() == 95 {
+				got = append(got, p)
+			}
+		})
+	}
+	b.Set(10)
+	if len(got) != 0 {
+		t.Fatal("want", "no milestones", "got", got)
+	}
+	b.Set(95)
+	b.Set(99)
+	b.Set(50)
+	b.Set(95)
+	if want := "[25 50 75 90]"; fmt.Sprint(got) != want {
+		t.Fatal("want", want, "got", got)
+	}
+
+	// failed bars reach no milestones
+	got = nil
+	b = NewBar(10)
+	b.OnMilestone(50, func(b *Bar) { got = append(got, 50) })
+	b.Fail(errors.New("boom"))
+	b.Finish()
+	if len(got) != 0 {
+		t.Fatal("want", "no milestones", "got", got)
+	}
+}
+
+func TestBarOnMilestoneContainer(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	progress := New()
+	progress.SetOut(ioutil.Discard)
+	progress.SetClock(clock)
+	b := progress.AddBar(100).AutoAdvance(10 * time.Second)
+	var summary string
+	b.OnMilestone(50, func(b *Bar) { summary = progress.Summary() })
+	progress.print(false)
+	clock.Advance(5 * time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		progress.print(false)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("want", "Summary to return", "got", "deadlock")
+	}
+	if summary == "" {
+		t.Fatal("want", "summary", "got", summary)
+	}
+}
+
+func TestBarOnMilestoneKeepCompleted(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	progress := New()
+	progress.SetOut(ioutil.Discard)
+	progress.SetClock(clock)
+	progress.KeepCompleted(true)
+	b := progress.AddBar(100).AutoAdvance(10 * time.Second)
+	b.OnMilestone(100, func(b *Bar) { progress.RemoveBar(b) })
+	progress.print(false)
+	clock.Advance(time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		progress.print(false)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("want", "RemoveBar to return", "got", "deadlock")
+	}
+	if len(progress.Bars) != 0 {
+		t.Fatal("want", 0, "got", len(progress.Bars))
+	}
+}
@@ -0,0 +1,101 @@
+package uiprogress
+
+import "errors"
+
+// PhaseSep is the default character marking the end of each phase of a bar, see NewPhasedBar
+var PhaseSep = '|'
+
+// ErrUnknownPhase is returned when setting the progress of a phase the bar does not have
+var ErrUnknownPhase = errors.New("errors: phase out of range")
+
+// Phase is a named stage of a bar created with NewPhasedBar
+type Phase struct {
+	// Name is the name of the phase, rendered by PrependPhase
+	Name string
+
+	// Weight is the share of the bar taken by the phase, in units of the bar. A weight below 1 counts as 1.
+	Weight int
+}
+
+// NewPhasedBar returns a bar divided into phases, such as download, extract and verify, with the end of every
+// phase but the last marked on the bar with PhaseSep. The total of the bar is the sum of the weights, so
+// weights such as 100 allow finer progress within each phase. Progress is set with SetPhaseProgress, or with
+// Set and Add as usual.
+func NewPhasedBar(phases []Phase) *Bar {
+	ps := make([]Phase, len(phases))
+	total := 0
+	for i, p := range phases {
+		if p.Weight < 1 {
+			p.Weight = 1
+		}
+		ps[i] = p
+		total += p.Weight
+	}
+	b := NewBar(total)
+	b.phases = ps
+	b.PhaseSep = PhaseSep
+	return b
+}
+
+// SetPhaseProgress sets the bar to done out of total within phase i, counting the phases before it as
+// completed. A total of 0 or less completes the phase. It returns ErrUnknownPhase when the bar has no phase i.
+func (b *Bar) SetPhaseProgress(i, done, total int) error {
+	if i < 0 || i >= len(b.phases) {
+		return ErrUnknownPhase
+	}
+	start := 0
+	for _, p := range b.phases[:i] {
+		start += p.Weight
+	}
+	weight := b.phases[i].Weight
+	n := weight
+	if total > 0 && done < total {
+		if done < 0 {
+			done = 0
+		}
+		n, _ = mulDiv(done, weight, total)
+	}
+	return b.set(start+n, true)
+}
+
+// CurrentPhase returns the index and the phase the bar is in, which is the last phase once the bar is
+// completed. It returns -1 for a bar without phases.
+func (b *Bar) CurrentPhase() (int, Phase) {
+	if len(b.phases) == 0 {
+		return -1, Phase{}
+	}
+	current, _ := b.state()
+	end := 0
+	for i, p := range b.phases {
+		if end += p.Weight; current < end {
+			return i, p
+		}
+	}
+	last := len(b.phases) - 1
+	return last, b.phases[last]
+}
+
+// PrependPhase prepends the name of the current phase to the progress bar, see NewPhasedBar
+func (b *Bar) PrependPhase() *Bar {
+	b.PrependFunc(func(b *Bar) string {
+		_, p := b.CurrentPhase()
+		return p.Name
+	})
+	return b
+}
+
+// markPhases marks the end of every phase but the last on the width cells of a bar with the given total
+func (b *Bar) markPhases(cells []rune, width, total int) {
+	if total <= 0 {
+		return
+	}
+	end := 0
+	for _, p := range b.phases[:len(b.phases)-1] {
+		if end += p.Weight; end >= total {
+			return
+		}
+		if at, _ := mulDiv(end, width, total); at > 0 {
+			cells[at-1] = b.PhaseSep
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package uiprogress
+
+import "testing"
+
+func TestPhasedBar(t *testing.T) {
+	b := NewPhasedBar([]Phase{{"download", 50}, {"extract", 30}, {"verify", 20}}).PrependPhase()
+	b.Width = 12
+	if b.Total != 100 {
+		t.Fatal("want", 100, "got", b.Total)
+	}
+	if got, want := b.String(), "download [----|--|--]"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	if err := b.SetPhaseProgress(1, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if b.Current() != 65 {
+		t.Fatal("want", 65, "got", b.Current())
+	}
+	if got, want := b.String(), "extract [====|>-|--]"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	b.SetPhaseProgress(2, 1, 0)
+	if i, p := b.CurrentPhase(); i != 2 || p.Name != "verify" || !b.IsCompleted() {
+		t.Fatal("want", 2, "verify", "got", i, p.Name)
+	}
+	if err := b.SetPhaseProgress(3, 0, 1); err != ErrUnknownPhase {
+		t.Fatal("want", ErrUnknownPhase, "got", err)
+	}
+	if i, _ := NewBar(10).CurrentPhase(); i != -1 {
+		t.Fatal("want", -1, "got", i)
+	}
+}
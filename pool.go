@@ -0,0 +1,28 @@
+package uiprogress
+
+import "sync"
+
+var barPool = sync.Pool{
+	New: func() interface{} {
+		return NewBar(0)
+	},
+}
+
+// GetBar returns a bar with the given total from a pool of bars, which reduces allocations when many
+// short-lived bars are created. The bar has the same defaults as one returned by NewBar, including a
+// negative total being set to UnknownTotal.
+func GetBar(total int) *Bar {
+	if total < 0 {
+		total = UnknownTotal
+	}
+	b := barPool.Get().(*Bar)
+	b.Total = total
+	return b
+}
+
+// PutBar resets the bar and returns it to the pool used by GetBar. The bar must be removed from any
+// Progress and must not be used after calling PutBar.
+func PutBar(b *Bar) {
+	b.reset(0)
+	barPool.Put(b)
+}
@@ -0,0 +1,21 @@
+package uiprogress
+
+import "testing"
+
+func TestBarPool(t *testing.T) {
+	b := GetBar(10)
+	b.Width = 10
+	b.AppendCompleted()
+	b.Set(5)
+	PutBar(b)
+
+	b = GetBar(20)
+	if b.Total != 20 || b.Current() != 0 || b.Width != Width || len(b.appendFuncs) != 0 {
+		t.Fatal("want", "a reset bar", "got", b.Total, b.Current(), b.Width, len(b.appendFuncs))
+	}
+	PutBar(b)
+
+	if b = GetBar(-5); b.Total != UnknownTotal || b.String() != NewBar(-5).String() {
+		t.Fatal("want", UnknownTotal, "got", b.Total)
+	}
+}
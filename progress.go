@@ -1,9 +1,12 @@
 package uiprogress
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,6 +19,9 @@ var Out = os.Stdout
 // RefreshInterval in the default time duration to wait for refreshing the output
 var RefreshInterval = time.Millisecond * 10
 
+// NonTTYStep is the default percent step at which progress is printed when the output is not a terminal
+var NonTTYStep = 10
+
 // defaultProgress is the default progress
 var defaultProgress = New()
 
@@ -33,27 +39,52 @@ type Progress struct {
 	// RefreshInterval in the time duration to wait for refreshing the output
 	RefreshInterval time.Duration
 
-	lw     *uilive.Writer
-	ticker *time.Ticker
-	tdone  chan bool
-	mtx    *sync.RWMutex
+	lw          *uilive.Writer
+	buf         bytes.Buffer
+	plain       bytes.Buffer
+	err         error
+	hideCursor  bool
+	redraw      RedrawStrategy
+	defaults    func(b *Bar)
+	outs        []output
+	clock       Clock
+	keep        bool
+	maxLines    int
+	frozen      map[*Bar]bool
+	less        func(a, b *Bar) bool
+	summaryOnly bool
+	taskbar     bool
+	ttyOut      io.Writer
+	tty         bool
+	step        int
+	steps       map[*Bar]int
+	ticker      *time.Ticker
+	tdone       chan bool
+	mtx         *sync.RWMutex
+
+	// changedMtx guards changed, which is closed when a bar changes while Wait is waiting
+	changedMtx sync.Mutex
+	changed    chan struct{}
 }
 
 // New returns a new progress bar with defaults
 func New() *Progress {
-	lw := uilive.New()
-	lw.Out = Out
-
-	return &Progress{
+	p := &Progress{
 		Width:           Width,
 		Out:             Out,
 		Bars:            make([]*Bar, 0),
 		RefreshInterval: RefreshInterval,
 
-		tdone: make(chan bool),
-		lw:    uilive.New(),
-		mtx:   &sync.RWMutex{},
+		tdone:  make(chan bool),
+		lw:     uilive.New(),
+		step:   NonTTYStep,
+		steps:  make(map[*Bar]int),
+		frozen: make(map[*Bar]bool),
+		mtx:    &sync.RWMutex{},
 	}
+	// frames are assembled in buf and written to Out at once, see print
+	p.lw.Out = &p.buf
+	return p
 }
 
 // AddBar creates a new progress bar and adds it to the default progress container
@@ -61,6 +92,11 @@ func AddBar(total int) *Bar {
 	return defaultProgress.AddBar(total)
 }
 
+// ApplyDefaults sets f to be applied to every bar created with AddBar afterwards, see Progress.ApplyDefaults
+func ApplyDefaults(f func(b *Bar)) {
+	defaultProgress.ApplyDefaults(f)
+}
+
 // Start starts the rendering the progress of progress bars using the DefaultProgress. It listens for updates using `bar.Set(n)` and new bars when added using `AddBar`
 func Start() {
 	defaultProgress.Start()
@@ -81,7 +117,23 @@ func (p *Progress) SetOut(o io.Writer) {
 	defer p.mtx.Unlock()
 
 	p.Out = o
-	p.lw.Out = o
+}
+
+// output is a writer frames are copied to, see AddOut
+type output struct {
+	w     io.Writer
+	plain bool
+}
+
+// AddOut adds w as a writer every frame written to Out is also written to, such as to keep a log of the
+// progress next to the bars on the terminal. When plain is set, the bars are rendered without colors or the
+// escape sequences that move the cursor, so each frame is written below the previous one as plain text, see
+// Bar.PlainString. The output written with Bypass is copied as is. A writer that fails is not written to
+// again, and its error does not stop the rendering to Out.
+func (p *Progress) AddOut(w io.Writer, plain bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.outs = append(p.outs, output{w: w, plain: plain})
 }
 
 func (p *Progress) SetRefreshInterval(interval time.Duration) {
@@ -90,43 +142,696 @@ func (p *Progress) SetRefreshInterval(interval time.Duration) {
 	p.RefreshInterval = interval
 }
 
+// SetNonTTYStep sets the percent step at which progress is printed when the output is not a terminal.
+// Instead of rendering animated bars, a line such as "file.zip: 50%" is printed whenever a bar crosses a
+// multiple of step. Writers other than *os.File are assumed to be terminals.
+func (p *Progress) SetNonTTYStep(step int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.step = step
+}
+
+// isTerminal returns true when the output is a terminal, caching the result for the current writer
+func (p *Progress) isTerminal() bool {
+	if p.Out != p.ttyOut {
+		p.ttyOut, p.tty = p.Out, true
+		if f, ok := p.Out.(*os.File); ok {
+			fi, err := f.Stat()
+			p.tty = err == nil && fi.Mode()&os.ModeCharDevice != 0
+		}
+	}
+	return p.tty
+}
+
+// terminalState returns the state given to the bars by setTerminal. Callers must hold the lock.
+func (p *Progress) terminalState() int8 {
+	if p.isTerminal() {
+		return 1
+	}
+	return -1
+}
+
+// printSteps prints a line for every bar that crossed a step since the last call
+func (p *Progress) printSteps() {
+	if p.step <= 0 {
+		return
+	}
+	for _, bar := range p.Bars {
+		if bar.TotalUnknown() {
+			continue
+		}
+		step := int(bar.CompletedPercent()) / p.step * p.step
+		if step > p.steps[bar] {
+			p.steps[bar] = step
+			line := fmt.Sprintf("%s%d%%\n", bar.prepended(false), step)
+			p.buf.WriteString(line)
+			if p.plainOuts() {
+				p.plain.WriteString(line)
+			}
+		}
+	}
+}
+
+// SetHideCursor sets whether the cursor is hidden while each frame is drawn, which avoids the cursor
+// flickering across the bars on slow terminals
+func (p *Progress) SetHideCursor(enabled bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.hideCursor = enabled
+}
+
+// RedrawStrategy is how each frame replaces the previous one on the terminal, see SetRedrawStrategy
+type RedrawStrategy int
+
+const (
+	// CursorUp moves the cursor up over the previous frame and draws the new frame in its place. Output
+	// printed to the terminal other than with Bypass breaks the layout.
+	CursorUp RedrawStrategy = iota
+
+	// ClearScreen clears the screen and draws the frame at the top, which keeps working when other output is
+	// printed to the terminal but erases that output. Completed bars are not kept, see KeepCompleted.
+	ClearScreen
+
+	// AppendOnly prints every frame below the previous one without moving the cursor, for dumb terminals
+	// and logs
+	AppendOnly
+)
+
+// clearScreen moves the cursor to the top left corner and clears the screen
+const clearScreen = "\x1b[H\x1b[2J"
+
+// SetRedrawStrategy sets how each frame replaces the previous one on a terminal. Defaults to CursorUp.
+func (p *Progress) SetRedrawStrategy(s RedrawStrategy) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.redraw = s
+}
+
+// KeepCompleted sets whether completed bars are kept on screen above the bars in progress. A bar is rendered
+// one last time when it completes and is never redrawn after that, so completed bars accumulate as a history
+// while the bars in progress keep updating below them.
+func (p *Progress) KeepCompleted(enabled bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.keep = enabled
+}
+
+// SetClock sets the clock of the container, which times the refreshes and is given to every bar in the
+// container, including the bars added later. By default, the container uses DefaultClock and bars keep
+// their own clock.
+func (p *Progress) SetClock(c Clock) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.clock = c
+	for _, bar := range p.Bars {
+		bar.SetClock(c)
+	}
+}
+
+// SetMaxLines limits the number of bars rendered to n, which is unlimited when 0. When there are more bars,
+// the bars with the highest Priority are shown, and among bars of equal priority the most recently updated
+// ones. The bars shown keep the order they are rendered in, see SetSort.
+func (p *Progress) SetMaxLines(n int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.maxLines = n
+}
+
+// visible returns the bars to render among bars, see SetMaxLines. Callers must hold the lock.
+func (p *Progress) visible(bars []*Bar) []*Bar {
+	if p.maxLines <= 0 || len(bars) <= p.maxLines {
+		return bars
+	}
+	ranked := make([]*Bar, len(bars))
+	copy(ranked, bars)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Priority != ranked[j].Priority {
+			return ranked[i].Priority > ranked[j].Priority
+		}
+		return ranked[i].lastUpdate() > ranked[j].lastUpdate()
+	})
+	shown := make(map[*Bar]bool, p.maxLines)
+	for _, bar := range ranked[:p.maxLines] {
+		shown[bar] = true
+	}
+	out := make([]*Bar, 0, p.maxLines)
+	for _, bar := range bars {
+		if shown[bar] {
+			out = append(out, bar)
+		}
+	}
+	return out
+}
+
+// SetSort sets the function used to order the bars before each render. The sort is stable, so bars
+// that compare equal keep the order they were added in. A nil less renders bars in the order added.
+func (p *Progress) SetSort(less func(a, b *Bar) bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.less = less
+}
+
+// SortByPercentDesc orders bars with the most completed bars first
+func SortByPercentDesc(a, b *Bar) bool {
+	return a.CompletedPercent() > b.CompletedPercent()
+}
+
+// SummaryOnly sets whether the progress renders a single summary line instead of one line per bar.
+// This is useful when tracking more bars than can be practically displayed.
+func (p *Progress) SummaryOnly(enabled bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.summaryOnly = enabled
+}
+
+// Summary returns a single line aggregating all the bars, e.g. "12/50 tasks, 63% overall, 4.2MiB/s".
+// The rate is formatted using the UnitFormatter of the first bar.
+func (p *Progress) Summary() string {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return p.summary()
+}
+
+func (p *Progress) summary() string {
+	var done int
+	var rate float64
+	for _, bar := range p.Bars {
+		if bar.IsCompleted() {
+			done++
+		}
+		rate += bar.Rate()
+	}
+	format := UnitFormatter(DefaultFormatter)
+	if len(p.Bars) > 0 {
+		format = p.Bars[0].UnitFormatter
+	}
+	return fmt.Sprintf("%d/%d tasks, %.f%% overall, %s", done, len(p.Bars), p.overallPercent(), NewRateFormatter(format)(rate))
+}
+
+// OverallPercent returns the percent completed across all the bars, weighted by their totals. Bars with an
+// unknown total are not counted. It returns 0 when there is no total to measure against.
+func (p *Progress) OverallPercent() float64 {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return p.overallPercent()
+}
+
+func (p *Progress) overallPercent() float64 {
+	var current, total int
+	for _, bar := range p.Bars {
+		c, t := bar.state()
+		if t < 0 {
+			continue
+		}
+		current += c
+		total += t
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(current) / float64(total) * 100
+}
+
+// OverallETA returns the estimated time until every bar completes, from the amount remaining across the bars
+// and their combined rate. The rate of completed bars is only used when no bar in progress has a rate yet,
+// such as when the next bar has not started. Bars with an unknown total are not counted. It returns 0 when
+// there is nothing left or no rate to estimate from.
+func (p *Progress) OverallETA() time.Duration {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	var remaining int
+	var active, all float64
+	for _, bar := range p.Bars {
+		current, total := bar.state()
+		if total < 0 {
+			continue
+		}
+		rate := bar.etaRate()
+		all += rate
+		if current < total {
+			remaining += total - current
+			active += rate
+		}
+	}
+	rate := active
+	if rate <= 0 {
+		rate = all
+	}
+	if remaining == 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(remaining) / rate * float64(time.Second))
+}
+
+// SetTaskbarProgress sets whether the overall percent is reported to the terminal using the OSC 9;4
+// sequence, which terminals such as Windows Terminal, ConEmu and WezTerm show in the taskbar or title bar.
+// It is a no-op on terminals that are not known to support the sequence.
+func (p *Progress) SetTaskbarProgress(enabled bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.taskbar = enabled && taskbarSupported()
+}
+
+// taskbarSupported returns true when the terminal is known to support OSC 9;4 progress sequences
+func taskbarSupported() bool {
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("ConEmuANSI") == "ON" || os.Getenv("TERM_PROGRAM") == "WezTerm"
+}
+
 // AddBar creates a new progress bar and adds to the container
 func (p *Progress) AddBar(total int) *Bar {
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
-	bar := NewBar(total)
+	bar := p.configure(NewBar(total))
+	p.add(bar)
+	return bar
+}
+
+// newBar returns a bar configured like the bars created with AddBar without adding it, so the bar can be
+// configured further before it is added with AddConfiguredBar and rendered
+func (p *Progress) newBar(total int) *Bar {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.configure(NewBar(total))
+}
+
+// configure sets the width of the container and the defaults set with ApplyDefaults on bar. Callers must
+// hold the lock.
+func (p *Progress) configure(bar *Bar) *Bar {
 	bar.Width = p.Width
-	p.Bars = append(p.Bars, bar)
+	p.applyDefaults(bar)
+	return bar
+}
+
+// ApplyDefaults sets f to be applied to every bar created with AddBar and AddBarForFile afterwards, such as
+// to add the same decorators to every bar. Further decorators and settings can still be added to each bar.
+// Bars added with AddConfiguredBar are left as configured. A nil f applies nothing. f is called with the
+// container locked, so it must not call the methods of the container.
+func (p *Progress) ApplyDefaults(f func(b *Bar)) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.defaults = f
+}
+
+// applyDefaults applies the function set with ApplyDefaults to bar. Callers must hold the lock.
+func (p *Progress) applyDefaults(bar *Bar) {
+	if p.defaults != nil {
+		p.defaults(bar)
+	}
+}
+
+// AddConfiguredBar adds a bar created and configured by the caller to the container and returns it. Unlike
+// AddBar, the Width and every other setting of the bar are kept, so the bar can be fully set up before it is
+// rendered. A bar must not be added to more than one container. The bar is not validated, see AddBarChecked.
+func (p *Progress) AddConfiguredBar(bar *Bar) *Bar {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.add(bar)
 	return bar
 }
 
+// AddBarChecked is like AddConfiguredBar, but returns the error of bar.Validate without adding the bar when
+// the bar is not valid
+func (p *Progress) AddBarChecked(bar *Bar) (*Bar, error) {
+	if err := bar.Validate(); err != nil {
+		return nil, err
+	}
+	return p.AddConfiguredBar(bar), nil
+}
+
+// add adds the bar to the container. Callers must hold the lock.
+func (p *Progress) add(bar *Bar) {
+	if p.clock != nil {
+		bar.SetClock(p.clock)
+	}
+	p.Bars = append(p.Bars, bar)
+	bar.setNotify(p.notify)
+	bar.setTerminal(p.terminalState())
+	p.reindex()
+}
+
+// reindex updates the position of every bar among the bars of the container. Callers must hold the lock.
+func (p *Progress) reindex() {
+	for i, bar := range p.Bars {
+		bar.setIndex(i+1, len(p.Bars))
+	}
+}
+
+// RemoveBar removes the bar from the container. It returns false when the bar is not in the container.
+func (p *Progress) RemoveBar(bar *Bar) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for i, b := range p.Bars {
+		if b == bar {
+			p.Bars = append(p.Bars[:i:i], p.Bars[i+1:]...)
+			delete(p.steps, bar)
+			delete(p.frozen, bar)
+			bar.setIndex(0, 0)
+			bar.setNotify(nil)
+			bar.setTerminal(0)
+			p.reindex()
+			p.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// Wait blocks until every bar of the container is completed or failed, or removed from the container,
+// without polling
+func (p *Progress) Wait() {
+	for {
+		p.changedMtx.Lock()
+		if p.changed == nil {
+			p.changed = make(chan struct{})
+		}
+		changed := p.changed
+		p.changedMtx.Unlock()
+
+		if p.done() {
+			return
+		}
+		<-changed
+	}
+}
+
+// notify wakes up Wait when a bar changes. It must not take any other lock, since bars call it holding their own.
+func (p *Progress) notify() {
+	p.changedMtx.Lock()
+	defer p.changedMtx.Unlock()
+	if p.changed != nil {
+		close(p.changed)
+		p.changed = nil
+	}
+}
+
+// done returns true when every bar is completed or failed
+func (p *Progress) done() bool {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	for _, bar := range p.Bars {
+		if !bar.IsCompleted() && bar.Err() == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// AddBarForFile creates a bar for reading f, see NewBarForFile, and adds it to the container
+func (p *Progress) AddBarForFile(f fs.File) (*Bar, io.Reader, error) {
+	bar, r, err := NewBarForFile(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.configure(bar)
+	if err := bar.Validate(); err != nil {
+		return nil, nil, err
+	}
+	p.add(bar)
+	return bar, r, nil
+}
+
 // Listen listens for updates and renders the progress bars
 func (p *Progress) Listen() {
 	for {
 
 		p.mtx.Lock()
 		interval := p.RefreshInterval
+		clock := p.clock
 		p.mtx.Unlock()
+		if clock == nil {
+			clock = DefaultClock
+		}
 
 		select {
-		case <-time.After(interval):
-			p.print()
+		case <-clock.After(interval):
+			p.print(false)
+			if p.Err() != nil {
+				// the output is gone, so rendering stops until Stop is called
+				<-p.tdone
+				close(p.tdone)
+				return
+			}
 		case <-p.tdone:
-			p.print()
+			p.print(true)
 			close(p.tdone)
 			return
 		}
 	}
 }
 
-func (p *Progress) print() {
+// print renders the bars. Unless force is set, the frame is skipped when no bar changed since the last one.
+// The whole frame is written to Out with a single Write, so the terminal never shows a partial frame.
+func (p *Progress) print(force bool) {
 	p.mtx.Lock()
-	defer p.mtx.Unlock()
+	bars := append([]*Bar(nil), p.Bars...)
+	p.frame(force)
+	p.mtx.Unlock()
+	dispatch(bars)
+}
+
+// dispatch runs the callbacks of the stalls and milestones reached while rendering bars, which run once the
+// container is unlocked, so they may call the methods of the container
+func dispatch(bars []*Bar) {
+	for _, bar := range bars {
+		bar.dispatch()
+	}
+}
+
+// frame renders and writes a frame, see print. Callers must hold the lock.
+func (p *Progress) frame(force bool) {
+	if p.err != nil {
+		return
+	}
+	// Out may have changed since the bars were added
+	state := p.terminalState()
 	for _, bar := range p.Bars {
-		fmt.Fprintln(p.lw, bar.String())
+		bar.setTerminal(state)
+	}
+	dirty := force
+	for _, bar := range p.Bars {
+		if bar.takeDirty() {
+			dirty = true
+		}
+	}
+	if !dirty {
+		return
+	}
+	if !p.isTerminal() {
+		p.printSteps()
+		p.writeFrame()
+		return
+	}
+	if p.hideCursor {
+		p.buf.WriteString("\x1b[?25l")
+	}
+	var w io.Writer = p.lw
+	switch p.redraw {
+	case ClearScreen:
+		p.buf.WriteString(clearScreen)
+		w = &p.buf
+	case AppendOnly:
+		w = &p.buf
 	}
-	p.lw.Flush()
+	var bars []*Bar
+	if !p.summaryOnly {
+		bars = p.sortedBars()
+		if p.keep && p.redraw != ClearScreen {
+			bars = p.freeze(bars)
+		}
+	}
+	p.writeLines(w, p.plainFrame(), p.visible(bars), 0)
+	if p.redraw == CursorUp {
+		p.lw.Flush()
+	}
+	if p.taskbar {
+		fmt.Fprintf(&p.buf, "\x1b]9;4;1;%d\x07", int(p.overallPercent()))
+	}
+	if p.hideCursor {
+		p.buf.WriteString("\x1b[?25h")
+	}
+	p.writeFrame()
+}
+
+// RenderFrame returns a frame of the bars in their current state, rendered like the frames of the container
+// but without the escape sequences that move the cursor or hide it. Lines are cut to width columns when width
+// is positive. The frame is rendered right away, without starting the container or affecting what it draws
+// next, which together with SetClock allows tests of whole frames.
+func (p *Progress) RenderFrame(width int) []byte {
+	p.mtx.RLock()
+	var bars []*Bar
+	if !p.summaryOnly {
+		bars = p.visible(p.sortedBars())
+	}
+	var buf bytes.Buffer
+	p.writeLines(&buf, nil, bars, width)
+	p.mtx.RUnlock()
+	dispatch(bars)
+	return buf.Bytes()
+}
+
+// writeLines writes a line for each of bars, or the summary line with SummaryOnly, cut to width columns when
+// width is positive. The lines are also written to plain without escape sequences, unless plain is nil.
+// Callers must hold the lock.
+func (p *Progress) writeLines(w, plain io.Writer, bars []*Bar, width int) {
+	if p.summaryOnly {
+		summary := p.summary()
+		writeLine(w, []byte(summary), width)
+		if plain != nil {
+			writeLine(plain, []byte(summary), width)
+		}
+		return
+	}
+	for _, bar := range bars {
+		if plain == nil {
+			writeLine(w, bar.render(false), width)
+			continue
+		}
+		out, text := bar.renderLines(true, true)
+		writeLine(w, out, width)
+		writeLine(plain, text, width)
+	}
+}
+
+// writeLine writes line and a newline to w, cutting the line to width columns when width is positive
+func writeLine(w io.Writer, line []byte, width int) {
+	if width > 0 {
+		line = truncateWidth(line, width)
+	}
+	w.Write(append(line, '\n'))
+}
+
+// PlainFrame returns the bars of the container as printable text, one line per bar rendered by PlainString,
+// or the summary line with SummaryOnly. Unlike the rendered frames, it has none of the escape sequences that
+// move the cursor or report the progress to the terminal.
+func (p *Progress) PlainFrame() string {
+	p.mtx.RLock()
+	if p.summaryOnly {
+		defer p.mtx.RUnlock()
+		return p.summary() + "\n"
+	}
+	var buf bytes.Buffer
+	bars := p.visible(p.sortedBars())
+	for _, bar := range bars {
+		buf.Write(bar.render(true))
+		buf.WriteByte('\n')
+	}
+	p.mtx.RUnlock()
+	dispatch(bars)
+	return buf.String()
+}
+
+// PrintFinal writes the Report of every bar to w, one line per bar, such as to print a summary once the work
+// is done in scripts without animated output. The container does not need to be started.
+func (p *Progress) PrintFinal(w io.Writer) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	var buf bytes.Buffer
+	for _, bar := range p.sortedBars() {
+		buf.WriteString(bar.Report())
+		buf.WriteByte('\n')
+	}
+	w.Write(buf.Bytes())
+}
+
+// freeze writes the bars that completed since the last frame above the redrawn area and returns the bars
+// that are still in progress, see KeepCompleted. Callers must hold the lock.
+func (p *Progress) freeze(bars []*Bar) []*Bar {
+	live := make([]*Bar, 0, len(bars))
+	for _, bar := range bars {
+		if p.frozen[bar] {
+			continue
+		}
+		if bar.IsCompleted() {
+			// the callbacks due are dispatched by print once the container is unlocked
+			var w io.Writer = &p.buf
+			if p.redraw == CursorUp {
+				// bypassing the live writer moves its cursor below the frozen line for good
+				w = p.lw.Bypass()
+			}
+			p.writeLines(w, p.plainFrame(), []*Bar{bar}, 0)
+			p.frozen[bar] = true
+			continue
+		}
+		live = append(live, bar)
+	}
+	return live
+}
+
+// writeFrame writes the buffered output to Out. Callers must hold the lock.
+func (p *Progress) writeFrame() {
+	if p.buf.Len() > 0 {
+		if _, err := p.Out.Write(p.buf.Bytes()); err != nil && p.err == nil {
+			p.err = err
+		}
+		p.copyFrame(p.buf.Bytes(), p.plain.Bytes())
+		p.buf.Reset()
+		p.plain.Reset()
+	}
+}
+
+// plainOuts returns true when writers were added with AddOut to copy the frames to as plain text. Callers
+// must hold the lock.
+func (p *Progress) plainOuts() bool {
+	for _, o := range p.outs {
+		if o.plain {
+			return true
+		}
+	}
+	return false
+}
+
+// plainFrame returns the buffer the plain text of the frame is written to, or nil when no writer was added
+// with AddOut to copy it to. Callers must hold the lock.
+func (p *Progress) plainFrame() io.Writer {
+	if !p.plainOuts() {
+		return nil
+	}
+	return &p.plain
+}
+
+// copyFrame writes frame, or its plain text, to the writers added with AddOut, dropping those that fail.
+// Callers must hold the lock.
+func (p *Progress) copyFrame(frame, plain []byte) {
+	outs := p.outs[:0]
+	for _, o := range p.outs {
+		f := frame
+		if o.plain {
+			f = plain
+		}
+		if len(f) > 0 {
+			if _, err := o.w.Write(f); err != nil {
+				continue
+			}
+		}
+		outs = append(outs, o)
+	}
+	p.outs = outs
+}
+
+// Err returns the first error writing to Out, such as a broken pipe once the terminal is gone. The container
+// stops rendering after the error, and Stop returns as usual.
+func (p *Progress) Err() error {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return p.err
+}
+
+// sortedBars returns the bars in the order they are rendered, see SetSort
+func (p *Progress) sortedBars() []*Bar {
+	if p.less == nil {
+		return p.Bars
+	}
+	bars := make([]*Bar, len(p.Bars))
+	copy(bars, p.Bars)
+	sort.SliceStable(bars, func(i, j int) bool {
+		return p.less(bars[i], bars[j])
+	})
+	return bars
 }
 
 // Start starts the rendering the progress of progress bars. It listens for updates using `bar.Set(n)` and new bars when added using `AddBar`
@@ -138,9 +843,32 @@ func (p *Progress) Start() {
 func (p *Progress) Stop() {
 	p.tdone <- true
 	<-p.tdone
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.taskbar {
+		fmt.Fprint(p.Out, "\x1b]9;4;0\x07")
+	}
 }
 
 // Bypass returns a writer which allows non-buffered data to be written to the underlying output
 func (p *Progress) Bypass() io.Writer {
-	return p.lw.Bypass()
+	return &bypass{p: p, w: p.lw.Bypass()}
+}
+
+// bypass writes to Out right away rather than with the next frame
+type bypass struct {
+	p *Progress
+	w io.Writer
+}
+
+func (b *bypass) Write(data []byte) (int, error) {
+	b.p.mtx.Lock()
+	defer b.p.mtx.Unlock()
+	n, err := b.w.Write(data)
+	if b.p.plainOuts() {
+		b.p.plain.Write(data)
+	}
+	b.p.writeFrame()
+	return n, err
 }
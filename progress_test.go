@@ -2,11 +2,20 @@ package uiprogress
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
+	"unicode/utf8"
+
+	"github.com/gosuri/uiprogress/util/strutil"
+	"github.com/gosuri/uiprogress/util/testutil"
 )
 
 func TestStoppingPrintout(t *testing.T) {
@@ -43,3 +52,524 @@ func TestStoppingPrintout(t *testing.T) {
 		t.Errorf("Content that should be printed after stop not appearing on buffer.")
 	}
 }
+
+func TestProgressSort(t *testing.T) {
+	progress := New()
+	progress.Width = 10
+
+	progress.AddBar(10).PrependFunc(func(b *Bar) string { return "a" }).Set(2)
+	progress.AddBar(10).PrependFunc(func(b *Bar) string { return "b" }).Set(8)
+	progress.AddBar(10).PrependFunc(func(b *Bar) string { return "c" }).Set(2)
+	progress.SetSort(SortByPercentDesc)
+
+	want := "b [======>-]\na [>-------]\nc [>-------]\n"
+	if got := string(progress.RenderFrame(0)); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	want = "b [==…\na [>-…\nc [>-…\n"
+	if got := string(progress.RenderFrame(6)); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestProgressSummary(t *testing.T) {
+	progress := New()
+	var buffer = &bytes.Buffer{}
+	progress.SetOut(buffer)
+
+	progress.AddBar(10).Set(10)
+	progress.AddBar(30).Set(10)
+	progress.AddBar(10)
+
+	want := "1/3 tasks, 40% overall"
+	if got := progress.Summary(); !strings.HasPrefix(got, want) {
+		t.Fatal("want", want, "got", got)
+	}
+
+	progress.SummaryOnly(true)
+	progress.print(true)
+	if lines := strings.Count(buffer.String(), "\n"); lines != 1 {
+		t.Fatal("want", 1, "got", lines)
+	}
+	if !strings.HasPrefix(buffer.String(), want) {
+		t.Fatal("want", want, "got", buffer.String())
+	}
+}
+
+func TestProgressOverallPercent(t *testing.T) {
+	progress := New()
+	if got := progress.OverallPercent(); got != 0 {
+		t.Fatal("want", 0, "got", got)
+	}
+	progress.AddBar(10).Set(10)
+	progress.AddBar(90).Set(0)
+	if got := progress.OverallPercent(); got != 10 {
+		t.Fatal("want", 10, "got", got)
+	}
+}
+
+func TestProgressSkipsUnchangedFrames(t *testing.T) {
+	progress := New()
+	var buffer = &bytes.Buffer{}
+	progress.SetOut(buffer)
+	bar := progress.AddBar(10)
+
+	progress.print(false)
+	n := buffer.Len()
+	bar.Set(0)
+	progress.print(false)
+	if buffer.Len() != n {
+		t.Fatal("want", n, "got", buffer.Len())
+	}
+	bar.Set(10)
+	progress.print(false)
+	if buffer.Len() == n {
+		t.Fatal("want", "a new frame", "got", "none")
+	}
+}
+
+func TestProgressTaskbar(t *testing.T) {
+	defer os.Setenv("WT_SESSION", os.Getenv("WT_SESSION"))
+	os.Setenv("WT_SESSION", "1")
+
+	progress := New()
+	var buffer = &bytes.Buffer{}
+	progress.SetOut(buffer)
+	progress.SetTaskbarProgress(true)
+	progress.AddBar(10).Set(5)
+	progress.Start()
+	progress.Stop()
+
+	if !strings.Contains(buffer.String(), "\x1b]9;4;1;50\x07") {
+		t.Fatalf("want %q in %q", "\x1b]9;4;1;50\x07", buffer.String())
+	}
+	if !strings.HasSuffix(buffer.String(), "\x1b]9;4;0\x07") {
+		t.Fatalf("want suffix %q in %q", "\x1b]9;4;0\x07", buffer.String())
+	}
+}
+
+func TestProgressNonTTY(t *testing.T) {
+	f, err := ioutil.TempFile("", "uiprogress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	progress := New()
+	progress.SetOut(f)
+	progress.SetNonTTYStep(25)
+	bar := progress.AddBar(100).PrependFunc(func(b *Bar) string { return "file.zip:" })
+	for _, n := range []int{10, 30, 40, 80, 100} {
+		bar.Set(n)
+		progress.print(false)
+	}
+
+	out, _ := ioutil.ReadFile(f.Name())
+	if want := "file.zip: 25%\nfile.zip: 75%\nfile.zip: 100%\n"; string(out) != want {
+		t.Fatalf("want %q got %q", want, out)
+	}
+}
+
+func TestBarPrependIndex(t *testing.T) {
+	progress := New()
+	progress.Width = 5
+	bar1 := progress.AddBar(10).PrependIndex()
+	bar2 := progress.AddBar(10).PrependIndex()
+	bar3 := progress.AddBar(10).PrependIndex()
+	if got := bar2.String(); got != "[2/3] [---]" {
+		t.Fatal("want", "[2/3] [---]", "got", got)
+	}
+	progress.RemoveBar(bar1)
+	if got := bar3.String(); got != "[2/2] [---]" {
+		t.Fatal("want", "[2/2] [---]", "got", got)
+	}
+	if got := bar1.String(); got != "[---]" {
+		t.Fatal("want", "[---]", "got", got)
+	}
+}
+
+func TestProgressAddConfiguredBar(t *testing.T) {
+	progress := New()
+	progress.Width = 20
+	progress.AddBar(10)
+	bar := NewBar(10)
+	bar.Width = 5
+	if got := progress.AddConfiguredBar(bar.PrependIndex()); got != bar {
+		t.Fatal("want", bar, "got", got)
+	}
+	if got := bar.String(); got != "[2/2] [---]" {
+		t.Fatal("want", "[2/2] [---]", "got", got)
+	}
+}
+
+// writeCounter counts the calls to Write
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestProgressSingleWritePerFrame(t *testing.T) {
+	progress := New()
+	out := &writeCounter{}
+	progress.SetOut(out)
+	progress.SetHideCursor(true)
+	progress.Width = 5
+	for i := 0; i < 3; i++ {
+		progress.AddBar(10)
+	}
+	progress.print(true)
+	progress.print(true)
+	if out.writes != 2 {
+		t.Fatal("want", 2, "got", out.writes)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "\x1b[?25l[---]\n") || !strings.HasSuffix(got, "[---]\n\x1b[?25h") {
+		t.Fatalf("want frames wrapped in cursor hide and show, got %q", got)
+	}
+	fmt.Fprintln(progress.Bypass(), "log")
+	if out.writes != 3 {
+		t.Fatal("want", 3, "got", out.writes)
+	}
+}
+
+func TestProgressKeepCompleted(t *testing.T) {
+	progress := New()
+	var buffer = &bytes.Buffer{}
+	progress.SetOut(buffer)
+	progress.KeepCompleted(true)
+	progress.Width = 5
+	done := progress.AddBar(10).PrependFunc(func(b *Bar) string { return "a" })
+	progress.AddBar(10).PrependFunc(func(b *Bar) string { return "b" })
+	progress.print(true)
+	done.Set(10)
+	progress.print(true)
+	buffer.Reset()
+	progress.print(true)
+	// only the bar in progress is redrawn
+	if got := buffer.String(); strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "b [---]\n") {
+		t.Fatalf("want %q, got %q", "b [---]\n", got)
+	}
+}
+
+func TestProgressClock(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	progress := New()
+	var buffer = &bytes.Buffer{}
+	progress.SetOut(buffer)
+	progress.SetClock(clock)
+	progress.Width = 5
+	bar := progress.AddBar(10).AppendElapsed()
+	progress.Start()
+
+	waitForTimer := func() {
+		for clock.Waiters() == 0 {
+			runtime.Gosched()
+		}
+	}
+	waitForTimer()
+	bar.Set(1)
+	clock.Advance(time.Second)
+	bar.Set(2)
+	clock.Advance(progress.RefreshInterval)
+	waitForTimer()
+	progress.Stop()
+	if got := buffer.String(); !strings.HasSuffix(got, "[---]    1s\n") {
+		t.Fatalf("want %q, got %q", "[---]    1s\n", got)
+	}
+}
+
+func TestProgressOverallETA(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	progress := New()
+	progress.SetClock(clock)
+	if eta := progress.OverallETA(); eta != 0 {
+		t.Fatal("want", 0, "got", eta)
+	}
+	done, active := progress.AddBar(100), progress.AddBar(100)
+	progress.AddBar(200)
+	progress.AddBar(UnknownTotal).Set(50)
+	done.Set(1)
+	if eta := progress.OverallETA(); eta != 0 {
+		t.Fatal("want", 0, "got", eta)
+	}
+	clock.Advance(10 * time.Second)
+	done.Set(100)
+	// the completed bar gives the only rate, 10 per second, for the 300 remaining
+	if eta := progress.OverallETA(); eta != 30*time.Second {
+		t.Fatal("want", 30*time.Second, "got", eta)
+	}
+	active.Set(1)
+	clock.Advance(10 * time.Second)
+	active.Set(50)
+	// the bar in progress moves at 5 per second with 250 remaining
+	if eta := progress.OverallETA(); eta != 50*time.Second {
+		t.Fatal("want", 50*time.Second, "got", eta)
+	}
+}
+
+func TestProgressPlainFrame(t *testing.T) {
+	progress := New()
+	var buffer = &bytes.Buffer{}
+	progress.SetOut(buffer)
+	progress.SetHideCursor(true)
+	progress.Width = 5
+	progress.AddBar(10).AppendColoredPercent().Set(5)
+	progress.AddBar(10)
+	if got, want := progress.PlainFrame(), "[>--]  50%\n[---]\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	progress.print(true)
+	if got, want := strutil.StripANSI(buffer.String()), progress.PlainFrame(); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestProgressMaxLinesPriority(t *testing.T) {
+	progress := New()
+	progress.Width = 3
+	progress.SetMaxLines(3)
+	var bars []*Bar
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		name := name
+		bars = append(bars, progress.AddBar(10).PrependFunc(func(b *Bar) string { return name }))
+	}
+	bars[4].Priority = 1
+	for _, i := range []int{0, 1, 2, 3} {
+		bars[i].Incr()
+	}
+	if got, want := progress.PlainFrame(), "c [-]\nd [-]\ne [-]\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	bars[0].Incr()
+	if got, want := progress.PlainFrame(), "a [-]\nd [-]\ne [-]\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestProgressPrintFinal(t *testing.T) {
+	progress := New()
+	progress.Width = 12
+	progress.AddBar(10).Set(5)
+	progress.AddBar(UnknownTotal).Set(3)
+	var buffer bytes.Buffer
+	progress.PrintFinal(&buffer)
+	if got, want := buffer.String(), "[=====-----] 50% (5/10)\n[----------] (3)\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+// failingWriter fails every write, like a terminal that is gone
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, syscall.EPIPE
+}
+
+func TestProgressWriteError(t *testing.T) {
+	progress := New()
+	out := &failingWriter{}
+	progress.SetOut(out)
+	progress.SetRefreshInterval(time.Millisecond)
+	bar := progress.AddBar(10)
+	progress.Start()
+	for progress.Err() == nil {
+		bar.Incr()
+		time.Sleep(time.Millisecond)
+	}
+	bar.Incr()
+	time.Sleep(10 * time.Millisecond)
+	progress.Stop()
+	if err := progress.Err(); err != syscall.EPIPE {
+		t.Fatal("want", syscall.EPIPE, "got", err)
+	}
+	if out.writes != 1 {
+		t.Fatal("want", 1, "got", out.writes)
+	}
+}
+
+func TestProgressAddOut(t *testing.T) {
+	progress := New()
+	var term, styled, log bytes.Buffer
+	progress.SetOut(&term)
+	progress.SetHideCursor(true)
+	progress.Width = 5
+	progress.AddOut(&styled, false)
+	progress.AddOut(&log, true)
+	failing := &failingWriter{}
+	progress.AddOut(failing, true)
+	progress.taskbar = true
+	bar := progress.AddBar(2).AppendColoredPercent()
+	progress.print(true)
+	bar.Incr()
+	progress.print(false)
+	progress.Bypass().Write([]byte("log line\n"))
+
+	if styled.String() != term.String() || !strings.Contains(term.String(), "\x1b]9;4;1;50") {
+		t.Fatalf("want %q, got %q", term.String(), styled.String())
+	}
+	if got, want := log.String(), "[---]   0%\n[>--]  50%\nlog line\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	if progress.Err() != nil || failing.writes != 1 {
+		t.Fatal("want", nil, 1, "got", progress.Err(), failing.writes)
+	}
+}
+
+func TestProgressRedrawStrategy(t *testing.T) {
+	for _, tc := range []struct {
+		strategy RedrawStrategy
+		want     string
+	}{
+		{AppendOnly, "[---]\n[>--]\n"},
+		{ClearScreen, "\x1b[H\x1b[2J[---]\n\x1b[H\x1b[2J[>--]\n"},
+	} {
+		progress := New()
+		var buffer = &bytes.Buffer{}
+		progress.SetOut(buffer)
+		progress.SetRedrawStrategy(tc.strategy)
+		progress.Width = 5
+		bar := progress.AddBar(10)
+		progress.print(true)
+		bar.Set(5)
+		progress.print(true)
+		if got := buffer.String(); got != tc.want {
+			t.Fatalf("want %q, got %q", tc.want, got)
+		}
+	}
+}
+
+// fakeTerminal is a screen interpreting the cursor movements and erasures the container writes
+type fakeTerminal struct {
+	lines    [][]rune
+	row, col int
+}
+
+func (term *fakeTerminal) Write(p []byte) (int, error) {
+	for i := 0; i < len(p); {
+		if p[i] == '\x1b' {
+			n := strutil.EscapeLen(p[i:])
+			seq := string(p[i : i+n])
+			i += n
+			switch {
+			case strings.HasSuffix(seq, "A"):
+				if term.row > 0 {
+					term.row--
+				}
+			case seq == "\x1b[2K":
+				term.line()
+				term.lines[term.row] = nil
+			}
+			continue
+		}
+		r, n := utf8.DecodeRune(p[i:])
+		i += n
+		switch r {
+		case '\r':
+			term.col = 0
+		case '\n':
+			term.row, term.col = term.row+1, 0
+		default:
+			line := term.line()
+			for len(line) <= term.col {
+				line = append(line, ' ')
+			}
+			line[term.col] = r
+			term.lines[term.row], term.col = line, term.col+1
+		}
+	}
+	return len(p), nil
+}
+
+// line returns the line of the cursor, adding lines up to it
+func (term *fakeTerminal) line() []rune {
+	for len(term.lines) <= term.row {
+		term.lines = append(term.lines, nil)
+	}
+	return term.lines[term.row]
+}
+
+func (term *fakeTerminal) String() string {
+	lines := make([]string, len(term.lines))
+	for i, line := range term.lines {
+		lines[i] = string(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestBarSetWidth(t *testing.T) {
+	progress := New()
+	term := &fakeTerminal{}
+	progress.SetOut(term)
+	progress.Width = 12
+	bar := progress.AddBar(10).AppendCompleted()
+	bar.Set(5)
+	progress.print(false)
+	for _, tc := range []struct {
+		width int
+		want  string
+	}{
+		{6, "[=>--]  50%"},
+		{12, "[====>-----]  50%"},
+		{0, ""},
+	} {
+		if err := bar.SetWidth(tc.width); err != nil {
+			t.Fatal(err)
+		}
+		progress.print(false)
+		if tc.width == 0 {
+			// the line is filled, see Width
+			tc.want = bar.String()
+		}
+		if got := term.String(); got != tc.want {
+			t.Fatalf("want %q, got %q", tc.want, got)
+		}
+	}
+	if err := bar.SetWidth(-1); !errors.Is(err, ErrInvalidBar) || bar.Width != 0 {
+		t.Fatal("want", ErrInvalidBar, 0, "got", err, bar.Width)
+	}
+}
+
+func TestProgressWait(t *testing.T) {
+	progress := New()
+	done, failed, removed := progress.AddBar(100), progress.AddBar(100), progress.AddBar(100)
+	returned := make(chan struct{})
+	go func() {
+		progress.Wait()
+		close(returned)
+	}()
+	for i := 0; i < 100; i++ {
+		done.Incr()
+	}
+	failed.Fail(errors.New("failed"))
+	select {
+	case <-returned:
+		t.Fatal("want", "Wait to block", "got", "returned")
+	case <-time.After(10 * time.Millisecond):
+	}
+	progress.RemoveBar(removed)
+	<-returned
+}
+
+func TestProgressApplyDefaults(t *testing.T) {
+	progress := New()
+	progress.Width = 5
+	progress.ApplyDefaults(func(b *Bar) {
+		b.PrependCompleted().AppendFunc(func(b *Bar) string { return "default" })
+	})
+	bar := progress.AddBar(10).AppendFunc(func(b *Bar) string { return "own" })
+	bar.Set(5)
+	if got, want := bar.String(), " 50% [>--] default own"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	if got := progress.AddConfiguredBar(NewBar(10)).String(); strings.Contains(got, "default") {
+		t.Fatal("want", "no defaults", "got", got)
+	}
+}
@@ -0,0 +1,149 @@
+package uiprogress
+
+import "time"
+
+const (
+	// rateWindow is the default span of recent progress behind InstantRate, see SetRateWindow
+	rateWindow = 5 * time.Second
+
+	// rateResolution is the default span of the updates merged into a single sample
+	rateResolution = 250 * time.Millisecond
+
+	// maxRateSamples bounds the samples kept by a bar, coarsening the resolution of long windows
+	maxRateSamples = 1024
+)
+
+// rateSample is the progress made from one update, or several close updates, of a bar
+type rateSample struct {
+	at    time.Time
+	delta int
+}
+
+// sampleRing keeps the samples of the last window, overwriting the oldest sample once full. The zero value
+// uses rateWindow and rateResolution.
+type sampleRing struct {
+	samples    []rateSample
+	next       int
+	window     time.Duration
+	resolution time.Duration
+}
+
+// span returns the window and the resolution of the ring
+func (r *sampleRing) span() (window, resolution time.Duration) {
+	if r.window <= 0 {
+		return rateWindow, rateResolution
+	}
+	return r.window, r.resolution
+}
+
+// resize sets the window and the resolution, keeping the newest samples that fit
+func (r *sampleRing) resize(window, resolution time.Duration) {
+	if resolution <= 0 {
+		resolution = rateResolution
+	}
+	if window < 2*resolution {
+		window = 2 * resolution
+	}
+	if window/resolution > maxRateSamples-1 {
+		resolution = window / (maxRateSamples - 1)
+	}
+	size := int(window/resolution) + 1
+	var kept []rateSample
+	if n := len(r.samples); n > 0 {
+		kept = make([]rateSample, 0, size)
+		for i := n - size; i < n; i++ {
+			if i >= 0 {
+				kept = append(kept, r.samples[(r.next+i)%n])
+			}
+		}
+	}
+	r.samples, r.next, r.window, r.resolution = kept, 0, window, resolution
+}
+
+// record adds the progress delta made at t
+func (r *sampleRing) record(t time.Time, delta int) {
+	if delta == 0 {
+		return
+	}
+	window, resolution := r.span()
+	if r.samples == nil {
+		r.samples = make([]rateSample, 0, int(window/resolution)+1)
+	}
+	if n := len(r.samples); n > 0 {
+		last := &r.samples[(r.next+n-1)%n]
+		if t.Before(last.at) {
+			// the clock was set back, and the sample cannot be placed
+			return
+		}
+		if t.Sub(last.at) < resolution {
+			last.delta += delta
+			return
+		}
+	}
+	if len(r.samples) < cap(r.samples) {
+		r.samples = append(r.samples, rateSample{at: t, delta: delta})
+		return
+	}
+	r.samples[r.next] = rateSample{at: t, delta: delta}
+	r.next = (r.next + 1) % len(r.samples)
+}
+
+// rate returns the progress per second of the samples in the window ending at now. The progress of the
+// first sample is made before its time and is left out, unless older samples show progress before the window.
+func (r *sampleRing) rate(now time.Time) float64 {
+	window, _ := r.span()
+	cutoff := now.Add(-window)
+	var start time.Time
+	sum, older := 0, false
+	for i := range r.samples {
+		s := r.samples[(r.next+i)%len(r.samples)]
+		switch {
+		case s.at.Before(cutoff):
+			older = true
+		case start.IsZero() && older:
+			start = cutoff
+			sum += s.delta
+		case start.IsZero():
+			start = s.at
+		default:
+			sum += s.delta
+		}
+	}
+	span := now.Sub(start)
+	if start.IsZero() || span <= 0 || sum <= 0 {
+		return 0
+	}
+	return float64(sum) / span.Seconds()
+}
+
+// SetRateWindow sets the span of the recent progress behind InstantRate to window, with the updates made
+// within resolution of each other merged into a single sample. A longer window smooths out bursts, while a
+// shorter one follows changes in speed sooner. The window is at least twice the resolution, and the
+// resolution of long windows is coarsened to keep at most about a thousand samples. Defaults to 5s and 250ms.
+func (b *Bar) SetRateWindow(window, resolution time.Duration) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.rates.resize(window, resolution)
+	return b
+}
+
+// InstantRate returns the progress per second over the last few seconds, which follows changes in speed
+// unlike the average given by Rate. Progress from Set, Add and Incr alike is counted.
+func (b *Bar) InstantRate() float64 {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.rates.rate(b.now())
+}
+
+// InstantRateString returns the formatted string representation of the instantaneous rate, e.g. "4.20MiB/s"
+func (b *Bar) InstantRateString() string {
+	return NewRateFormatter(b.UnitFormatter)(b.InstantRate())
+}
+
+// AppendInstantRate appends the progress per second over the last few seconds to the progress bar
+func (b *Bar) AppendInstantRate() *Bar {
+	b.AppendFunc(func(b *Bar) string {
+		return b.InstantRateString()
+	})
+	return b
+}
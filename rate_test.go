@@ -0,0 +1,118 @@
+package uiprogress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestBarInstantRate(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(1000).SetClock(clock)
+	if rate := b.InstantRate(); rate != 0 {
+		t.Fatal("want", 0, "got", rate)
+	}
+	b.Set(10)
+	for _, n := range []int{30, 50} {
+		clock.Advance(time.Second)
+		b.Set(n)
+	}
+	if rate := b.InstantRate(); rate != 20 {
+		t.Fatal("want", 20, "got", rate)
+	}
+
+	// the rate follows the recent progress, not the average since the start
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Second)
+		b.Set(b.Current() + 100)
+	}
+	if rate := b.InstantRate(); rate != 100 {
+		t.Fatal("want", 100, "got", rate)
+	}
+	if rate := b.Rate(); rate >= 100 {
+		t.Fatal("want", "an average below 100", "got", rate)
+	}
+
+	clock.Advance(rateWindow)
+	if rate := b.InstantRate(); rate != 0 {
+		t.Fatal("want", 0, "got", rate)
+	}
+}
+
+func TestSampleRingBounded(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(UnknownTotal).SetClock(clock)
+	for i := 0; i < 1000; i++ {
+		clock.Advance(rateResolution)
+		b.Incr()
+	}
+	if n, max := len(b.rates.samples), int(rateWindow/rateResolution)+1; n > max {
+		t.Fatal("want", max, "got", n)
+	}
+	if rate := b.InstantRate(); rate != 4 {
+		t.Fatal("want", 4, "got", rate)
+	}
+}
+
+func TestBarSetRateWindow(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(UnknownTotal).SetClock(clock).SetRateWindow(6*time.Hour, time.Millisecond)
+	for i := 0; i < 30000; i++ {
+		clock.Advance(time.Second)
+		b.Incr()
+	}
+	if n := len(b.rates.samples); n > maxRateSamples {
+		t.Fatal("want", maxRateSamples, "got", n)
+	}
+	if window, resolution := b.rates.span(); window != 6*time.Hour || resolution < window/maxRateSamples {
+		t.Fatal("want", 6*time.Hour, "got", window, resolution)
+	}
+
+	// the window is at least twice the resolution
+	b.SetRateWindow(time.Second, time.Second)
+	if window, _ := b.rates.span(); window != 2*time.Second {
+		t.Fatal("want", 2*time.Second, "got", window)
+	}
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		b.Incr()
+	}
+	if rate := b.InstantRate(); rate != 1 {
+		t.Fatal("want", 1, "got", rate)
+	}
+}
+
+func TestBarRateWindowSmoothing(t *testing.T) {
+	spread := func(window time.Duration) float64 {
+		clock := testutil.NewFakeClock(time.Unix(0, 0))
+		b := NewBar(UnknownTotal).SetClock(clock).SetRateWindow(window, time.Second)
+		min, max := -1.0, 0.0
+		for i := 0; i < 60; i++ {
+			clock.Advance(time.Second)
+			// a burst every fifth second
+			if i%5 == 0 {
+				b.Add(100)
+			} else {
+				b.Add(1)
+			}
+			if i < 20 {
+				continue
+			}
+			rate := b.InstantRate()
+			if rate < 0 {
+				t.Fatal("want", "a positive rate", "got", rate)
+			}
+			if min < 0 || rate < min {
+				min = rate
+			}
+			if rate > max {
+				max = rate
+			}
+		}
+		return max - min
+	}
+	if short, long := spread(2*time.Second), spread(20*time.Second); long >= short {
+		t.Fatal("want", "a long window smoother than", short, "got", long)
+	}
+}
@@ -0,0 +1,172 @@
+package uiprogress
+
+import (
+	"io"
+	"sort"
+	"sync"
+)
+
+// ReadAtUpdater wraps input so that every ReadAt advances the bar by the number of bytes read. Ranges may
+// be read concurrently and complete in any order. To discount a range that is going to be read again,
+// call Add with the negated length of the range before retrying, or use DedupRanges. With Throttle, call
+// Flush on the returned *ReaderAtProgressor after the last read.
+func (b *Bar) ReadAtUpdater(input io.ReaderAt, opts ...ReaderOption) io.ReaderAt {
+	return &ReaderAtProgressor{
+		readerConfig: newReaderConfig(opts),
+		bar:          b,
+		input:        input,
+	}
+}
+
+// ReaderAtProgressor is an io.ReaderAt that advances a bar as data is read. It is safe for concurrent use
+// when the wrapped reader is, as io.ReaderAt requires.
+type ReaderAtProgressor struct {
+	readerConfig
+
+	bar     *Bar
+	input   io.ReaderAt
+	ranges  rangeSet
+	pending syncPending
+}
+
+func (p *ReaderAtProgressor) ReadAt(into []byte, off int64) (int, error) {
+	amt, err := p.input.ReadAt(into, off)
+	p.chunk(into[:amt])
+	if cerr := p.pending.add(p.bar, &p.readerConfig, p.counted(&p.ranges, off, amt), err); cerr != nil && err == nil {
+		return amt, cerr
+	}
+	return amt, err
+}
+
+// Flush advances the bar by the bytes held back by Throttle. It should be called after the last read.
+func (p *ReaderAtProgressor) Flush() error {
+	return p.pending.flush(p.bar, p.clamp)
+}
+
+// WriteAtUpdater wraps output so that every WriteAt advances the bar by the number of bytes written, such as
+// for the chunks of a parallel download written at their offsets. Ranges may be written concurrently and
+// complete in any order. See DedupRanges for ranges written more than once. With Throttle, call Flush on the
+// returned *WriterAtProgressor after the last write.
+func (b *Bar) WriteAtUpdater(output io.WriterAt, opts ...ReaderOption) io.WriterAt {
+	return &WriterAtProgressor{
+		readerConfig: newReaderConfig(opts),
+		bar:          b,
+		output:       output,
+	}
+}
+
+// WriterAtProgressor is an io.WriterAt that advances a bar as data is written. It is safe for concurrent use
+// when the wrapped writer is, which io.WriterAt requires for writes to ranges that do not overlap.
+type WriterAtProgressor struct {
+	readerConfig
+
+	bar     *Bar
+	output  io.WriterAt
+	ranges  rangeSet
+	pending syncPending
+}
+
+func (p *WriterAtProgressor) WriteAt(b []byte, off int64) (int, error) {
+	amt, err := p.output.WriteAt(b, off)
+	p.chunk(b[:amt])
+	if cerr := p.pending.add(p.bar, &p.readerConfig, p.counted(&p.ranges, off, amt), err); cerr != nil && err == nil {
+		return amt, cerr
+	}
+	return amt, err
+}
+
+// Flush advances the bar by the bytes held back by Throttle. It should be called after the last write.
+func (p *WriterAtProgressor) Flush() error {
+	return p.pending.flush(p.bar, p.clamp)
+}
+
+// syncPending is a pending safe for concurrent use by the ranges transferred at once
+type syncPending struct {
+	mtx     sync.Mutex
+	pending pending
+}
+
+// add adds n bytes like pending.add, advancing bar by all the pending bytes right away when err is set
+func (p *syncPending) add(bar *Bar, c *readerConfig, n int, err error) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if err != nil {
+		p.pending.n += n
+		return p.pending.flush(bar, c.clamp)
+	}
+	return p.pending.add(bar, c, n)
+}
+
+// flush advances bar by the pending bytes
+func (p *syncPending) flush(bar *Bar, clamp bool) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.pending.flush(bar, clamp)
+}
+
+// DedupRanges makes ReadAtUpdater and WriteAtUpdater count every offset once, so that ranges transferred
+// again, such as when a chunk is retried, or overlapping ranges do not advance the bar twice. The ranges
+// seen are kept in memory, merged when adjacent.
+func DedupRanges() ReaderOption {
+	return func(c *readerConfig) {
+		c.dedup = true
+	}
+}
+
+// counted returns the bytes of the n at off to add to the bar, which are the ones not in s with DedupRanges
+func (c *readerConfig) counted(s *rangeSet, off int64, n int) int {
+	if !c.dedup || n <= 0 {
+		return n
+	}
+	return int(s.add(off, off+int64(n)))
+}
+
+// byteRange is the range of offsets from start to end, exclusive
+type byteRange struct {
+	start, end int64
+}
+
+// rangeSet is a set of offsets kept as sorted ranges that neither overlap nor touch. It is safe for
+// concurrent use.
+type rangeSet struct {
+	mtx    sync.Mutex
+	ranges []byteRange
+}
+
+// add adds the offsets from start to end to the set and returns how many of them were not in it
+func (s *rangeSet) add(start, end int64) int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	// the ranges from i to j overlap or touch the new one and are merged into it
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].end >= start })
+	j := i
+	added := end - start
+	for ; j < len(s.ranges) && s.ranges[j].start <= end; j++ {
+		r := s.ranges[j]
+		lo, hi := r.start, r.end
+		if lo < start {
+			lo = start
+		}
+		if hi > end {
+			hi = end
+		}
+		if hi > lo {
+			added -= hi - lo
+		}
+		if r.start < start {
+			start = r.start
+		}
+		if r.end > end {
+			end = r.end
+		}
+	}
+	merged := byteRange{start, end}
+	if i == j {
+		s.ranges = append(s.ranges, byteRange{})
+		copy(s.ranges[i+1:], s.ranges[i:])
+	} else {
+		s.ranges = append(s.ranges[:i+1], s.ranges[j:]...)
+	}
+	s.ranges[i] = merged
+	return added
+}
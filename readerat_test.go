@@ -0,0 +1,140 @@
+package uiprogress
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadAtUpdater(t *testing.T) {
+	const segments, size = 16, 4096
+	b := NewBar(segments * size)
+	r := b.ReadAtUpdater(strings.NewReader(strings.Repeat("x", segments*size)), Clamp())
+
+	var wg sync.WaitGroup
+	for i := 0; i < segments; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			buf := make([]byte, 512)
+			for off := i * size; off < (i+1)*size; off += len(buf) {
+				if _, err := r.ReadAt(buf, int64(off)); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if b.Current() != b.Total {
+		t.Fatal("want", b.Total, "got", b.Current())
+	}
+
+	// clamping keeps a repeated range from exceeding the total
+	r.ReadAt(make([]byte, 512), 0)
+	if b.Current() != b.Total {
+		t.Fatal("want", b.Total, "got", b.Current())
+	}
+}
+
+func TestWriteAtUpdaterDedup(t *testing.T) {
+	const segments, size = 8, 1024
+	b := NewBar(segments * size)
+	f, err := ioutil.TempFile("", "uiprogress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	w := b.WriteAtUpdater(f, DedupRanges())
+
+	var wg sync.WaitGroup
+	for i := 0; i < segments; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// every segment is written twice, overlapping the next one
+			buf := make([]byte, size+size/2)
+			for j := 0; j < 2; j++ {
+				off := int64(i * size)
+				if i == segments-1 {
+					buf = buf[:size]
+				}
+				if _, err := w.WriteAt(buf, off); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if b.Current() != b.Total {
+		t.Fatal("want", b.Total, "got", b.Current())
+	}
+}
+
+func TestWriteAtUpdaterOptions(t *testing.T) {
+	const segments, size = 8, 1024
+	b := NewBar(segments * size)
+	f, err := ioutil.TempFile("", "uiprogress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	var chunked int64
+	w := b.WriteAtUpdater(f, Throttle(segments*size, time.Hour),
+		OnChunk(func(p []byte, n int) { atomic.AddInt64(&chunked, int64(n)) }))
+
+	var wg sync.WaitGroup
+	for i := 0; i < segments; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := w.WriteAt(make([]byte, size/2), int64(i*size)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if b.Current() != 0 || chunked != segments*size/2 {
+		t.Fatal("want", 0, segments*size/2, "got", b.Current(), chunked)
+	}
+	if err := w.(*WriterAtProgressor).Flush(); err != nil || b.Current() != segments*size/2 {
+		t.Fatal("want", segments*size/2, "got", b.Current(), err)
+	}
+
+	b = NewBar(size)
+	r := b.ReadAtUpdater(strings.NewReader("abc"), Throttle(size, time.Hour),
+		OnChunk(func(p []byte, n int) { atomic.AddInt64(&chunked, int64(n)) }))
+	// the bytes held back are added on error, such as EOF
+	if _, err := r.ReadAt(make([]byte, 8), 0); err == nil {
+		t.Fatal("want", "EOF", "got", err)
+	}
+	if b.Current() != 3 || chunked != segments*size/2+3 {
+		t.Fatal("want", 3, segments*size/2+3, "got", b.Current(), chunked)
+	}
+}
+
+func TestRangeSet(t *testing.T) {
+	var s rangeSet
+	for _, c := range []struct {
+		start, end, added int64
+	}{
+		{10, 20, 10},
+		{30, 40, 10},
+		{15, 35, 10},
+		{0, 50, 20},
+		{20, 30, 0},
+		{50, 60, 10},
+	} {
+		if got := s.add(c.start, c.end); got != c.added {
+			t.Fatal("want", c.added, "got", got, "adding", c.start, c.end)
+		}
+	}
+	if len(s.ranges) != 1 || s.ranges[0] != (byteRange{0, 60}) {
+		t.Fatal("want", "[{0 60}]", "got", s.ranges)
+	}
+}
@@ -0,0 +1,69 @@
+package uiprogress
+
+import (
+	"io"
+)
+
+// RetryUpdater wraps input like ReadUpdater and keeps count of the bytes read in the current attempt, so
+// that retry logic that reads the data again from the start can take back the progress of a failed attempt
+// using ResetAttempt or Rewind.
+func (b *Bar) RetryUpdater(input io.Reader, opts ...ReaderOption) *RetryProgressor {
+	return &RetryProgressor{
+		ReadProgressor: &ReadProgressor{
+			readerConfig: newReaderConfig(opts),
+			bar:          b,
+			input:        input,
+		},
+	}
+}
+
+// RetryProgressor is an io.Reader that advances a bar as data is read and can undo the progress of the
+// current attempt
+type RetryProgressor struct {
+	*ReadProgressor
+
+	attempt int
+}
+
+func (p *RetryProgressor) Read(into []byte) (int, error) {
+	n, err := p.ReadProgressor.Read(into)
+	p.attempt += n
+	return n, err
+}
+
+// WriteTo hides the WriteTo of ReadProgressor, so that io.Copy reads through Read and every byte is counted
+// towards the attempt
+func (p *RetryProgressor) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{p})
+}
+
+// ResetAttempt moves the bar back by all the bytes read since the reader was created or the last call to
+// ResetAttempt, and starts a new attempt. It must be called before reading the data again and not
+// concurrently with Read.
+func (p *RetryProgressor) ResetAttempt() {
+	p.Rewind(p.attempt)
+	p.attempt = 0
+}
+
+// Rewind moves the bar back by n of the bytes read in the current attempt, for retry logic that only reads
+// the last n bytes again. n is limited to the bytes read in the attempt. It must not be called concurrently
+// with Read.
+func (p *RetryProgressor) Rewind(n int) {
+	if n > p.attempt {
+		n = p.attempt
+	}
+	if n <= 0 {
+		return
+	}
+	p.attempt -= n
+	p.eof = false
+
+	// bytes held back by Throttle never reached the bar, so only the rest is taken back from it
+	held := p.pending.n
+	if held >= n {
+		p.pending.n -= n
+		return
+	}
+	p.pending.n = 0
+	p.bar.add(held-n, true)
+}
@@ -0,0 +1,71 @@
+package uiprogress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// failingReader fails with errFlaky after reading limit bytes of r
+type failingReader struct {
+	r     io.Reader
+	limit int
+}
+
+var errFlaky = errors.New("connection reset")
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.limit <= 0 {
+		return 0, errFlaky
+	}
+	if len(p) > f.limit {
+		p = p[:f.limit]
+	}
+	n, err := f.r.Read(p)
+	f.limit -= n
+	return n, err
+}
+
+func TestRetryUpdater(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 1000)
+	src := bytes.NewReader(data)
+	fr := &failingReader{r: src, limit: 300}
+	bar := NewBar(len(data))
+	r := bar.RetryUpdater(fr, Throttle(128, time.Hour))
+
+	for _, limit := range []int{700, len(data) + 1} {
+		if _, err := io.Copy(ioutil.Discard, r); err != errFlaky {
+			t.Fatal("want", errFlaky, "got", err)
+		}
+		r.ResetAttempt()
+		if bar.Current() != 0 {
+			t.Fatal("want", 0, "got", bar.Current())
+		}
+		src.Seek(0, io.SeekStart)
+		fr.limit = limit
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if bar.Current() != bar.Total {
+		t.Fatal("want", bar.Total, "got", bar.Current())
+	}
+}
+
+func TestRetryUpdaterRewind(t *testing.T) {
+	bar := NewBar(10)
+	r := bar.RetryUpdater(bytes.NewReader([]byte("0123456789")))
+	buf := make([]byte, 6)
+	io.ReadFull(r, buf)
+	r.Rewind(2)
+	if bar.Current() != 4 {
+		t.Fatal("want", 4, "got", bar.Current())
+	}
+	r.Rewind(10)
+	if bar.Current() != 0 {
+		t.Fatal("want", 0, "got", bar.Current())
+	}
+}
@@ -0,0 +1,68 @@
+package uiprogress
+
+import "time"
+
+// StallThreshold sets the time without progress after which the bar is stalled, see Stalled. A threshold of
+// 0, the default, disables the stall detection.
+func (b *Bar) StallThreshold(d time.Duration) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.stallAfter = d
+	b.dirty = true
+	return b
+}
+
+// OnStall registers f to be called once the bar becomes stalled, see StallThreshold. It is called once per
+// stall, and again when the bar stalls after making progress. Stalls are detected when the bar is rendered,
+// and the callbacks run outside of the locks of the bar and of its container, so they may call their methods,
+// such as Fail to abort or Progress.RemoveBar.
+func (b *Bar) OnStall(f func(*Bar)) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.onStall = append(b.onStall, f)
+	return b
+}
+
+// Stalled returns the time since the last progress of the bar when it exceeds the stall threshold, or 0 when
+// the bar is not stalled. Bars that have not started, are completed or failed are never stalled.
+func (b *Bar) Stalled() time.Duration {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.stalledFor()
+}
+
+// AppendStalled appends "stalled" and the time since the last progress once the bar is stalled, such as
+// "stalled 34s", and nothing otherwise, see StallThreshold
+func (b *Bar) AppendStalled() *Bar {
+	b.AppendFunc(func(b *Bar) string {
+		if d := b.Stalled(); d > 0 {
+			return "stalled " + b.TimeFormatter(d)
+		}
+		return ""
+	})
+	return b
+}
+
+// stalledFor is Stalled for callers holding the lock
+func (b *Bar) stalledFor() time.Duration {
+	if b.stallAfter <= 0 || b.progressed.IsZero() || b.err != nil || (b.Total >= 0 && b.current >= b.Total) {
+		return 0
+	}
+	if d := since(b.now(), b.progressed); d >= b.stallAfter {
+		return d
+	}
+	return 0
+}
+
+// checkStall keeps a stalled bar dirty, so the time shown by AppendStalled is updated, and queues the
+// callbacks of OnStall when the bar just stalled, to be run by unlock. Callers must hold the lock.
+func (b *Bar) checkStall() {
+	if b.stalledFor() == 0 {
+		return
+	}
+	b.dirty = true
+	if !b.stalled {
+		b.stalled = true
+		b.due = append(b.due, b.onStall...)
+	}
+}
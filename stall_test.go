@@ -0,0 +1,75 @@
+package uiprogress
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestBarStall(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	stalls := 0
+	b := NewBar(100).SetClock(clock).StallThreshold(10 * time.Second).AppendStalled()
+	b.OnStall(func(b *Bar) { stalls++ })
+	b.Width = 5
+	b.Incr()
+	clock.Advance(5 * time.Second)
+	if got, want := b.String(), "[---]"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	clock.Advance(29 * time.Second)
+	if got, want := b.String(), "[---] stalled 34s"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	b.Bytes()
+	if stalls != 1 {
+		t.Fatal("want", 1, "got", stalls)
+	}
+
+	// progress ends the stall and rearms the callback
+	b.Incr()
+	b.Bytes()
+	if b.Stalled() != 0 || stalls != 1 {
+		t.Fatal("want", 0, 1, "got", b.Stalled(), stalls)
+	}
+	clock.Advance(10 * time.Second)
+	b.Bytes()
+	if stalls != 2 {
+		t.Fatal("want", 2, "got", stalls)
+	}
+
+	// completed bars are not stalled
+	b.Finish()
+	clock.Advance(time.Minute)
+	if b.Stalled() != 0 {
+		t.Fatal("want", 0, "got", b.Stalled())
+	}
+}
+
+func TestBarOnStallRemoveBar(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	progress := New()
+	progress.SetOut(ioutil.Discard)
+	progress.SetClock(clock)
+	b := progress.AddBar(100).StallThreshold(time.Second)
+	b.OnStall(func(b *Bar) { progress.RemoveBar(b) })
+	b.Incr()
+	clock.Advance(time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		progress.print(false)
+		progress.RenderFrame(0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("want", "RemoveBar to return", "got", "deadlock")
+	}
+	if len(progress.Bars) != 0 {
+		t.Fatal("want", 0, "got", len(progress.Bars))
+	}
+}
@@ -0,0 +1,130 @@
+package uiprogress
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BarState is a snapshot of the progress of a bar, such as to checkpoint a long job and continue it later.
+// It holds none of the configuration of the bar, such as its decorators. Fields may be added in future
+// versions; unknown fields are ignored when decoding, so older and newer snapshots can be read.
+type BarState struct {
+	Current    int           `json:"current"`
+	Total      int           `json:"total"`
+	Secondary  int           `json:"secondary,omitempty"`
+	Items      int           `json:"items,omitempty"`
+	ItemsTotal int           `json:"items_total,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Completed  bool          `json:"completed"`
+
+	// Err is the message of the error the bar failed with, or empty when it has not failed
+	Err string `json:"error,omitempty"`
+}
+
+// State returns a consistent snapshot of the progress of the bar. It is safe to call while the bar is
+// rendered and updated.
+func (b *Bar) State() BarState {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	s := BarState{
+		Current:    b.current,
+		Total:      b.Total,
+		Secondary:  b.secondary,
+		Items:      b.items,
+		ItemsTotal: b.itemsTotal,
+		StartedAt:  b.TimeStarted,
+		Elapsed:    b.timeElapsed,
+		Completed:  b.Total >= 0 && b.current >= b.Total,
+	}
+	if b.err != nil {
+		s.Err = b.err.Error()
+	}
+	return s
+}
+
+// RestoreState sets the progress of the bar to s, keeping its configuration, so that it continues from
+// where the snapshot was taken. A failed bar is restored with an error carrying the same message. Negative
+// current and secondary values are restored as 0, and a negative total as UnknownTotal, like NewBar.
+func (b *Bar) RestoreState(s BarState) {
+	if s.Current < 0 {
+		s.Current = 0
+	}
+	if s.Secondary < 0 {
+		s.Secondary = 0
+	}
+	if s.Total < 0 {
+		s.Total = UnknownTotal
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.current, b.Total, b.secondary = s.Current, s.Total, s.Secondary
+	b.items, b.itemsTotal = s.Items, s.ItemsTotal
+	b.TimeStarted, b.timeElapsed, b.elapsedBase = s.StartedAt, s.Elapsed, 0
+	if b.err != nil {
+		// the channel of the old failure is closed, see failed
+		b.failc = nil
+	}
+	b.err = nil
+	if s.Err != "" {
+		b.err = errors.New(s.Err)
+		if b.failc != nil {
+			close(b.failc)
+		}
+	}
+	b.dirty = true
+	b.changed()
+}
+
+// MarshalJSON encodes the state of the bar, see State
+func (b *Bar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.State())
+}
+
+// UnmarshalJSON restores the state of the bar, see RestoreState. Decoding into a zero Bar sets it up like
+// NewBar first.
+func (b *Bar) UnmarshalJSON(data []byte) error {
+	var s BarState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if b.mtx == nil {
+		b.mtx = &sync.RWMutex{}
+		b.reset(s.Total)
+	}
+	b.RestoreState(s)
+	return nil
+}
+
+// BarCheckpoint is a snapshot of the progress of a bar meant to resume it after a restart, see Checkpoint
+type BarCheckpoint BarState
+
+// Checkpoint returns a snapshot of the progress of the bar to resume it with Restore, such as in another
+// process
+func (b *Bar) Checkpoint() BarCheckpoint {
+	return BarCheckpoint(b.State())
+}
+
+// Restore sets the progress of the bar to the checkpoint cp, keeping its configuration. Unlike RestoreState,
+// the time between the checkpoint and the restore is not counted: the time elapsed continues from the time
+// elapsed at the checkpoint, so the rate and the time estimates carry on as if the job was not interrupted.
+func (b *Bar) Restore(cp BarCheckpoint) *Bar {
+	started := !cp.StartedAt.IsZero()
+	b.RestoreState(BarState(cp))
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.TimeStarted = time.Time{}
+	if started {
+		b.TimeStarted = b.now()
+		b.elapsedBase = cp.Elapsed
+	}
+	return b
+}
+
+// NewBarFromCheckpoint returns a new bar resumed from the checkpoint cp, see Restore
+func NewBarFromCheckpoint(cp BarCheckpoint) *Bar {
+	return NewBar(cp.Total).Restore(cp)
+}
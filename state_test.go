@@ -0,0 +1,124 @@
+package uiprogress
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestBarJSON(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := NewBar(100).SetClock(clock)
+	b.Set(10)
+	clock.Advance(5 * time.Second)
+	b.Set(40)
+	b.SetItems(2, 5)
+	b.Fail(errors.New("disk full"))
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Bar
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := restored.State(), b.State(); got != want {
+		t.Fatal("want", want, "got", got)
+	}
+	if restored.Err() == nil || restored.Err().Error() != "disk full" {
+		t.Fatal("want", "disk full", "got", restored.Err())
+	}
+	restored.Width = 7
+	if err := restored.Set(100); err != nil || !restored.IsCompleted() {
+		t.Fatal("want", "completed", "got", restored.Current(), err)
+	}
+	if got := restored.String(); got != "[=====]" {
+		t.Fatal("want", "[=====]", "got", got)
+	}
+}
+
+func TestBarUnmarshalUnknownFields(t *testing.T) {
+	b := NewBar(1).AppendCompleted()
+	if err := json.Unmarshal([]byte(`{"current":3,"total":8,"future":{"x":1}}`), b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Current() != 3 || b.Total != 8 {
+		t.Fatal("want", 3, 8, "got", b.Current(), b.Total)
+	}
+	b.Width = 3
+	if got := b.String(); got != "[-]  38%" {
+		t.Fatal("want", "[-]  38%", "got", got)
+	}
+}
+
+func TestBarUnmarshalNegative(t *testing.T) {
+	var b Bar
+	if err := json.Unmarshal([]byte(`{"current":-5,"total":10,"secondary":-1}`), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Current() != 0 || b.Secondary() != 0 || b.CompletedPercent() != 0 {
+		t.Fatal("want", 0, "got", b.Current(), b.Secondary(), b.CompletedPercent())
+	}
+
+	b2 := NewBarFromCheckpoint(BarCheckpoint{Current: 3, Total: -7})
+	if b2.Total != UnknownTotal || b2.Current() != 3 {
+		t.Fatal("want", UnknownTotal, 3, "got", b2.Total, b2.Current())
+	}
+}
+
+func TestBarMarshalWhileUpdating(t *testing.T) {
+	b := NewBar(10000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for b.Incr() {
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		var s BarState
+		data, _ := json.Marshal(b)
+		json.Unmarshal(data, &s)
+		if s.Current > s.Total {
+			t.Fatal("want at most", s.Total, "got", s.Current)
+		}
+	}
+	<-done
+}
+
+func TestBarCheckpoint(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewBar(100).SetClock(clock)
+	b.Set(10)
+	clock.Advance(10 * time.Second)
+	b.Set(30)
+	data, err := json.Marshal(b.Checkpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the process restarts an hour later
+	clock.Advance(time.Hour)
+	var cp BarCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if b := NewBarFromCheckpoint(cp); b.Current() != 30 || b.Total != 100 {
+		t.Fatal("want", 30, 100, "got", b.Current(), b.Total)
+	}
+	resumed := NewBar(0).SetClock(clock).Restore(cp)
+	if resumed.TimeElapsed() != 10*time.Second || resumed.Current() != 30 {
+		t.Fatal("want", 10*time.Second, 30, "got", resumed.TimeElapsed(), resumed.Current())
+	}
+	clock.Advance(5 * time.Second)
+	resumed.Set(60)
+	if resumed.TimeElapsed() != 15*time.Second {
+		t.Fatal("want", 15*time.Second, "got", resumed.TimeElapsed())
+	}
+	if resumed.Rate() != 4 {
+		t.Fatal("want", 4, "got", resumed.Rate())
+	}
+}
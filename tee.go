@@ -0,0 +1,47 @@
+package uiprogress
+
+import (
+	"fmt"
+	"io"
+)
+
+// TeeError is returned by the reader of TeeUpdater when writing to the secondary writer fails
+type TeeError struct {
+	// Err is the error returned by the secondary writer, io.ErrShortWrite for short writes
+	Err error
+}
+
+func (e *TeeError) Error() string {
+	return fmt.Sprintf("tee write failure: %v", e.Err)
+}
+
+// Unwrap returns the error of the secondary writer
+func (e *TeeError) Unwrap() error {
+	return e.Err
+}
+
+// TeeUpdater is like ReadUpdater and also writes every chunk read from input to w, like io.TeeReader. A
+// failed or short write to w fails the read with a *TeeError. The bar always advances by the bytes
+// returned to the caller.
+func (b *Bar) TeeUpdater(input io.Reader, w io.Writer, opts ...ReaderOption) io.Reader {
+	return b.ReadUpdater(&teeReader{r: input, w: w}, opts...)
+}
+
+type teeReader struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (t *teeReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		wn, werr := t.w.Write(p[:n])
+		if werr == nil && wn < n {
+			werr = io.ErrShortWrite
+		}
+		if werr != nil {
+			return n, &TeeError{Err: werr}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,62 @@
+package uiprogress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// shortWriter writes at most n bytes per call without returning an error
+type shortWriter struct {
+	n int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return w.n, nil
+	}
+	return len(p), nil
+}
+
+func TestTeeUpdater(t *testing.T) {
+	data := strings.Repeat("x", 10000)
+	b := NewBar(len(data))
+	var copied bytes.Buffer
+	if _, err := io.Copy(ioutil.Discard, b.TeeUpdater(strings.NewReader(data), &copied)); err != nil {
+		t.Fatal(err)
+	}
+	if copied.String() != data || b.Current() != len(data) {
+		t.Fatal("want", len(data), "got", copied.Len(), b.Current())
+	}
+
+	writeErr := errors.New("write failed")
+	for _, tc := range []struct {
+		w    io.Writer
+		want error
+	}{
+		{&limitWriter{n: 0}, io.ErrShortWrite},
+		{&shortWriter{n: 10}, io.ErrShortWrite},
+		{&errWriter{err: writeErr}, writeErr},
+	} {
+		b := NewBar(len(data))
+		n, err := b.TeeUpdater(strings.NewReader(data), tc.w).Read(make([]byte, 100))
+		var terr *TeeError
+		if !errors.As(err, &terr) || !errors.Is(err, tc.want) {
+			t.Fatal("want", tc.want, "got", err)
+		}
+		if n != 100 || b.Current() != 100 {
+			t.Fatal("want", 100, "got", n, b.Current())
+		}
+	}
+}
+
+type errWriter struct {
+	err error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
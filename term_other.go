@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package uiprogress
+
+// terminalWidth returns the number of columns of the terminal attached to stdout
+func terminalWidth() (int, bool) {
+	return 0, false
+}
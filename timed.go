@@ -0,0 +1,53 @@
+package uiprogress
+
+import "time"
+
+// NewTimedBar returns a bar that fills itself over d, such as to show a fixed wait. The total is d in
+// milliseconds, rendered by DurationFormatterMillis. See AutoAdvance.
+func NewTimedBar(d time.Duration) *Bar {
+	b := NewBar(int(d / time.Millisecond))
+	b.UnitFormatter = DurationFormatterMillis
+	return b.AutoAdvance(d)
+}
+
+// AutoAdvance makes the bar fill itself over d from the first time it is rendered, setting the current value
+// to the share of the total matching the time elapsed whenever the bar is rendered, with or without a
+// container. The bar completes at the end of d, or earlier with Finish, and stops advancing once completed or
+// failed. It has no effect on a bar with an unknown total.
+func (b *Bar) AutoAdvance(d time.Duration) *Bar {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.autoFor, b.autoFrom = d, time.Time{}
+	b.dirty = true
+	return b
+}
+
+// autoAdvance moves a bar set with AutoAdvance along with the time elapsed. Callers must hold the lock.
+func (b *Bar) autoAdvance() {
+	if b.autoFor <= 0 || b.err != nil || b.Total < 0 || b.current >= b.Total {
+		return
+	}
+	if b.autoFrom.IsZero() {
+		b.autoFrom = b.now()
+		if !b.deadline.IsZero() {
+			// measured with the clock of the bar, which may differ from the one the bar was created with
+			b.autoFor = since(b.deadline, b.autoFrom)
+			b.Total = int(b.autoFor / time.Millisecond)
+			if b.Total <= 0 {
+				return
+			}
+		}
+	}
+	elapsed := since(b.now(), b.autoFrom)
+	n := b.Total
+	if ms := int(b.autoFor / time.Millisecond); ms > 0 && elapsed < b.autoFor {
+		n, _ = mulDiv(int(elapsed/time.Millisecond), b.Total, ms)
+	}
+	if !b.deadline.IsZero() && n >= b.Total {
+		// only Finish completes a deadline bar
+		n = b.Total - 1
+	}
+	if n != b.current {
+		b.advance(n)
+	}
+}
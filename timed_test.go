@@ -0,0 +1,49 @@
+package uiprogress
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gosuri/uiprogress/util/testutil"
+)
+
+func TestTimedBar(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	b := NewTimedBar(10 * time.Second).SetClock(clock).AppendFunc(func(b *Bar) string {
+		return b.FormattedCurrent()
+	})
+	b.Width = 12
+	if got, want := b.String(), "[----------] 0s"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	clock.Advance(5 * time.Second)
+	if got, want := b.String(), "[====>-----] 5s"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	clock.Advance(time.Minute)
+	if !b.takeDirty() || !b.IsCompleted() {
+		t.Fatal("want", "completed bar", "got", b.Current())
+	}
+
+	// finishing early or failing stops the updates
+	b = NewTimedBar(10 * time.Second).SetClock(clock)
+	b.Bytes()
+	clock.Advance(2 * time.Second)
+	b.Finish()
+	clock.Advance(time.Second)
+	b.Bytes()
+	if b.Current() != 10000 {
+		t.Fatal("want", 10000, "got", b.Current())
+	}
+	b = NewBar(100).SetClock(clock).AutoAdvance(10 * time.Second)
+	b.Bytes()
+	clock.Advance(2 * time.Second)
+	b.Bytes()
+	b.Fail(errors.New("unhealthy"))
+	clock.Advance(time.Second)
+	b.Bytes()
+	if b.Current() != 20 {
+		t.Fatal("want", 20, "got", b.Current())
+	}
+}
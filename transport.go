@@ -0,0 +1,93 @@
+package uiprogress
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Transport is an http.RoundTripper that tracks the download of every response body with a bar
+type Transport struct {
+	progress *Progress
+	base     http.RoundTripper
+	label    func(*http.Request) string
+}
+
+// TransportOption configures a Transport
+type TransportOption func(*Transport)
+
+// TransportLabel sets the function that returns the label prepended to the bar of a request. Defaults to
+// the request URL.
+func TransportLabel(f func(*http.Request) string) TransportOption {
+	return func(t *Transport) {
+		t.label = f
+	}
+}
+
+// NewTransport returns a Transport that sends requests with base, or http.DefaultTransport when base is
+// nil. The body of every successful response, other than for HEAD requests, is tracked with a bar sized
+// from the Content-Length, or an unknown total when the length is missing. The bar is added to p and removed
+// again once the body is closed. A request fails with the error of Bar.Validate when the defaults of p make
+// the bar invalid, see Progress.ApplyDefaults.
+func NewTransport(p *Progress, base http.RoundTripper, opts ...TransportOption) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		progress: p,
+		base:     base,
+		label: func(req *http.Request) string {
+			return req.URL.String()
+		},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, err
+	}
+
+	total := UnknownTotal
+	if resp.ContentLength >= 0 {
+		total = int(resp.ContentLength)
+	}
+	label := t.label(req)
+	bar := t.progress.newBar(total)
+	bar.UnitFormatter = BytesFormatter
+	bar.PrependFunc(func(b *Bar) string {
+		return label
+	})
+	if _, err := t.progress.AddBarChecked(bar); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = &transportBody{
+		ReadCloser: bar.ReadUpdater(resp.Body, CompleteOnClose()).(io.ReadCloser),
+		progress:   t.progress,
+		bar:        bar,
+	}
+	return resp, nil
+}
+
+// transportBody removes the bar from the container once the response body is closed
+type transportBody struct {
+	io.ReadCloser
+
+	progress *Progress
+	bar      *Bar
+	once     sync.Once
+}
+
+func (b *transportBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.progress.RemoveBar(b.bar)
+	})
+	return err
+}
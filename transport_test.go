@@ -0,0 +1,67 @@
+package uiprogress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportInvalidBar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("x"))
+	}))
+	defer srv.Close()
+	p := New()
+	p.ApplyDefaults(func(b *Bar) { b.Width = -1 })
+	client := &http.Client{Transport: NewTransport(p, nil)}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrInvalidBar) || len(p.Bars) != 0 {
+		t.Fatal("want", ErrInvalidBar, "got", err, len(p.Bars))
+	}
+}
+
+func TestTransport(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 3<<20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/", "/chunked"} {
+		p := New()
+		client := &http.Client{Transport: NewTransport(p, nil)}
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := io.Copy(ioutil.Discard, resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Bars) != 1 {
+			t.Fatal("want", 1, "got", len(p.Bars))
+		}
+		bar := p.Bars[0]
+		if bar.Current() != len(body) || bar.Total != len(body) || n != int64(len(body)) {
+			t.Fatal("want", len(body), "got", bar.Current(), bar.Total, n)
+		}
+		resp.Body.Close()
+		if len(p.Bars) != 0 {
+			t.Fatal("want", 0, "got", len(p.Bars))
+		}
+
+		if resp, err = client.Head(srv.URL + path); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if len(p.Bars) != 0 {
+			t.Fatal("want", 0, "got", len(p.Bars))
+		}
+	}
+}
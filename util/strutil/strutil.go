@@ -3,28 +3,34 @@ package strutil
 
 import (
 	"bytes"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // PadRight returns a new string of a specified length in which the end of the current string is padded with spaces or with a specified Unicode character.
+// The length is counted in runes, so strings with multi-byte characters are padded to the same width as ASCII ones.
 func PadRight(str string, length int, pad byte) string {
-	if len(str) >= length {
+	n := utf8.RuneCountInString(str)
+	if n >= length {
 		return str
 	}
 	buf := bytes.NewBufferString(str)
-	for i := 0; i < length-len(str); i++ {
+	for i := 0; i < length-n; i++ {
 		buf.WriteByte(pad)
 	}
 	return buf.String()
 }
 
 // PadLeft returns a new string of a specified length in which the beginning of the current string is padded with spaces or with a specified Unicode character.
+// The length is counted in runes, like PadRight.
 func PadLeft(str string, length int, pad byte) string {
-	if len(str) >= length {
+	n := utf8.RuneCountInString(str)
+	if n >= length {
 		return str
 	}
 	var buf bytes.Buffer
-	for i := 0; i < length-len(str); i++ {
+	for i := 0; i < length-n; i++ {
 		buf.WriteByte(pad)
 	}
 	buf.WriteString(str)
@@ -32,19 +38,20 @@ func PadLeft(str string, length int, pad byte) string {
 }
 
 // Resize resizes the string with the given length. It ellipses with '...' when the string's length exceeds
-// the desired length or pads spaces to the right of the string when length is smaller than desired
+// the desired length or pads spaces to the right of the string when length is smaller than desired. The
+// length is counted in runes.
 func Resize(s string, length uint) string {
 	n := int(length)
-	if len(s) == n {
+	if utf8.RuneCountInString(s) == n {
 		return s
 	}
 	// Pads only when length of the string smaller than len needed
 	s = PadRight(s, n, ' ')
-	if len(s) > n {
-		b := []byte(s)
+	if utf8.RuneCountInString(s) > n {
+		r := []rune(s)
 		var buf bytes.Buffer
 		for i := 0; i < n-3; i++ {
-			buf.WriteByte(b[i])
+			buf.WriteRune(r[i])
 		}
 		buf.WriteString("...")
 		s = buf.String()
@@ -59,3 +66,49 @@ func PrettyTime(t time.Duration) string {
 	}
 	return (t - (t % time.Second)).String()
 }
+
+// StripANSI returns s without its ANSI escape sequences, such as colors and cursor movements
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			i += EscapeLen([]byte(s[i:]))
+			continue
+		}
+		buf.WriteByte(s[i])
+		i++
+	}
+	return buf.String()
+}
+
+// EscapeLen returns the length of the ANSI escape sequence at the start of p, which starts with ESC. CSI
+// sequences end with a byte in the range @ to ~ and OSC sequences with BEL or ST; other sequences are two bytes
+// long. An unterminated sequence takes the rest of p.
+func EscapeLen(p []byte) int {
+	i := 1
+	if i >= len(p) {
+		return i
+	}
+	switch p[i] {
+	case '[':
+		for i++; i < len(p) && (p[i] < 0x40 || p[i] > 0x7e); i++ {
+		}
+		i++
+	case ']':
+		for i++; i < len(p) && p[i] != '\a' && p[i] != '\x1b'; i++ {
+		}
+		if i < len(p) && p[i] == '\x1b' {
+			i++
+		}
+		i++
+	default:
+		i++
+	}
+	if i > len(p) {
+		i = len(p)
+	}
+	return i
+}
@@ -40,3 +40,29 @@ func TestPrettyTime(t *testing.T) {
 		t.Fatal("want", "---", "got", got)
 	}
 }
+
+func TestPadMultibyte(t *testing.T) {
+	if got := PadLeft("1.2\u00a0MiB", 9, ' '); got != "  1.2\u00a0MiB" {
+		t.Fatalf("want %q, got %q", "  1.2\u00a0MiB", got)
+	}
+	if got := PadRight("ü", 3, '-'); got != "ü--" {
+		t.Fatal("want", "ü--", "got", got)
+	}
+	if got := Resize("größenänderung", 7); got != "größ..." {
+		t.Fatal("want", "größ...", "got", got)
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	for s, want := range map[string]string{
+		"plain":                                "plain",
+		"\x1b[31mred\x1b[0m":                   "red",
+		"\x1b]8;;http://x\x07link\x1b]8;;\x07": "link",
+		"\x1b[?25lbar\x1b[?25h":                "bar",
+		"cut\x1b[3":                            "cut",
+	} {
+		if got := StripANSI(s); got != want {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+	}
+}
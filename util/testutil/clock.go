@@ -0,0 +1,68 @@
+// Package testutil provides utilities for testing code that renders progress bars
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock whose time only moves when advanced. It implements uiprogress.Clock.
+type FakeClock struct {
+	mtx     sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewFakeClock returns a fake clock set to t
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the time of the clock
+func (c *FakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time of the clock once it is advanced by d or more
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, waiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the channels returned by After that are due
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// Waiters returns the number of channels returned by After that have not fired yet, allowing tests to wait
+// until the code under test is blocked on the clock
+func (c *FakeClock) Waiters() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.waiters)
+}
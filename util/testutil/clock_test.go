@@ -0,0 +1,25 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := NewFakeClock(start)
+	ch := c.After(time.Second)
+	c.Advance(time.Millisecond * 500)
+	select {
+	case <-ch:
+		t.Fatal("want", "no tick", "got", "tick")
+	default:
+	}
+	c.Advance(time.Millisecond * 500)
+	if got := <-ch; !got.Equal(start.Add(time.Second)) {
+		t.Fatal("want", start.Add(time.Second), "got", got)
+	}
+	if c.Waiters() != 0 {
+		t.Fatal("want", 0, "got", c.Waiters())
+	}
+}
@@ -0,0 +1,38 @@
+package uiprogress
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// ErrInvalidBar is wrapped by the errors returned by Bar.Validate
+var ErrInvalidBar = errors.New("errors: invalid bar")
+
+// Validate checks the configuration of the bar, returning an error wrapping ErrInvalidBar that describes
+// the first problem found: a negative Width, a WidthPercent outside of 0 to 100, fill or empty characters
+// that are not printable or that are the same, which hides the progress, and a missing UnitFormatter or
+// TimeFormatter. The container validates the bars added with AddBarChecked and AddBarForFile, and so do the
+// bars of Copy and Transport.
+func (b *Bar) Validate() error {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	fill, empty := glyph(b.FillRune, b.Fill), glyph(b.EmptyRune, b.Empty)
+	switch {
+	case b.Width < 0:
+		return fmt.Errorf("%w: width %d is negative", ErrInvalidBar, b.Width)
+	case b.WidthPercent < 0 || b.WidthPercent > 100:
+		return fmt.Errorf("%w: width percent %d is not between 0 and 100", ErrInvalidBar, b.WidthPercent)
+	case !unicode.IsPrint(fill):
+		return fmt.Errorf("%w: fill %q is not printable", ErrInvalidBar, fill)
+	case !unicode.IsPrint(empty):
+		return fmt.Errorf("%w: empty %q is not printable", ErrInvalidBar, empty)
+	case fill == empty:
+		return fmt.Errorf("%w: fill and empty are both %q", ErrInvalidBar, fill)
+	case b.UnitFormatter == nil:
+		return fmt.Errorf("%w: unit formatter is nil", ErrInvalidBar)
+	case b.TimeFormatter == nil:
+		return fmt.Errorf("%w: time formatter is nil", ErrInvalidBar)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package uiprogress
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBarValidate(t *testing.T) {
+	for _, c := range []struct {
+		configure func(b *Bar)
+		want      string
+	}{
+		{func(b *Bar) {}, ""},
+		{func(b *Bar) { b.Width = 0 }, ""},
+		{func(b *Bar) { b.Width = -3 }, "errors: invalid bar: width -3 is negative"},
+		{func(b *Bar) { b.WidthPercent = 120 }, "errors: invalid bar: width percent 120 is not between 0 and 100"},
+		{func(b *Bar) { b.FillRune = '\n' }, `errors: invalid bar: fill '\n' is not printable`},
+		{func(b *Bar) { b.Empty = 0 }, `errors: invalid bar: empty '\x00' is not printable`},
+		{func(b *Bar) { b.EmptyRune = '=' }, "errors: invalid bar: fill and empty are both '='"},
+		{func(b *Bar) { b.UnitFormatter = nil }, "errors: invalid bar: unit formatter is nil"},
+		{func(b *Bar) { b.TimeFormatter = nil }, "errors: invalid bar: time formatter is nil"},
+	} {
+		b := NewBar(10)
+		c.configure(b)
+		err := b.Validate()
+		if c.want == "" {
+			if err != nil {
+				t.Fatal("want", nil, "got", err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.want || !errors.Is(err, ErrInvalidBar) {
+			t.Fatal("want", c.want, "got", err)
+		}
+	}
+
+	if b := NewBar(-5); b.Total != UnknownTotal {
+		t.Fatal("want", UnknownTotal, "got", b.Total)
+	}
+}
+
+func TestProgressAddBarChecked(t *testing.T) {
+	p := New()
+	b := NewBar(10)
+	b.Width = -1
+	if _, err := p.AddBarChecked(b); !errors.Is(err, ErrInvalidBar) || len(p.Bars) != 0 {
+		t.Fatal("want", ErrInvalidBar, "got", err, len(p.Bars))
+	}
+	b.Width = 10
+	if got, err := p.AddBarChecked(b); err != nil || got != b || len(p.Bars) != 1 {
+		t.Fatal("want", b, "got", got, err)
+	}
+}
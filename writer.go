@@ -0,0 +1,39 @@
+package uiprogress
+
+import "io"
+
+// WriteUpdater wraps output so that every write advances the bar by the number of bytes written
+func (b *Bar) WriteUpdater(output io.Writer, opts ...ReaderOption) io.Writer {
+	return &WriteProgressor{
+		readerConfig: newReaderConfig(opts),
+		bar:          b,
+		output:       output,
+	}
+}
+
+// WriteProgressor is an io.Writer that advances a bar as data is written
+type WriteProgressor struct {
+	readerConfig
+
+	bar     *Bar
+	output  io.Writer
+	pending pending
+}
+
+func (p *WriteProgressor) Write(b []byte) (int, error) {
+	n, err := p.output.Write(b)
+	p.chunk(b[:n])
+	cerr := p.pending.add(p.bar, &p.readerConfig, n)
+	if err != nil {
+		cerr = p.Flush()
+	}
+	if cerr != nil && err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// Flush advances the bar by the bytes held back by Throttle. It should be called after the last write.
+func (p *WriteProgressor) Flush() error {
+	return p.pending.flush(p.bar, p.clamp)
+}